@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/config"
+	logger "go-geoip/common/loggger"
+	"go-geoip/controller"
+	"go-geoip/geoip"
+	"go-geoip/model"
+)
+
+// runCommand 执行子命令; 日志统一写到 stderr, 避免污染 stdout 上输出的数据
+func runCommand(args []string) {
+	logger.SetupLogger()
+	gin.DefaultWriter = gin.DefaultErrorWriter
+
+	switch args[0] {
+	case "lookup":
+		lookupCommand(args[1:])
+	case "update":
+		updateCommand(args[1:])
+	case "info":
+		infoCommand(args[1:])
+	case "export":
+		exportCommand(args[1:])
+	case "enrich":
+		enrichCommand(args[1:])
+	default:
+		logger.FatalLog(fmt.Sprintf("unknown command %q, see --help", args[0]))
+	}
+}
+
+type lookupErrorLine struct {
+	IP      string `json:"ip"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// lookupCommand go-geoip lookup [--rdns] <ip>... ; 参数为 - 时从 stdin 逐行读取 IP, 每个结果输出一行 JSON
+func lookupCommand(args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	rdns := fs.Bool("rdns", false, "include reverse DNS (PTR) hostnames")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		logger.FatalLog("usage: go-geoip lookup [--rdns] <ip>... | -")
+	}
+
+	ensureDatabases(cliOptions(true))
+
+	opts := controller.LookupOptions{RDNS: *rdns}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+
+	failed := false
+	lookup := func(ip string) {
+		info, err := controller.LookupIP(context.Background(), ip, opts)
+		if err != nil {
+			failed = true
+			apiErr := common.AsAPIError(err)
+			_ = enc.Encode(lookupErrorLine{IP: ip, Error: apiErr.Key, Message: apiErr.Error()})
+			return
+		}
+		_ = enc.Encode(info)
+	}
+
+	if fs.NArg() == 1 && fs.Arg(0) == "-" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if ip := strings.TrimSpace(scanner.Text()); ip != "" {
+				lookup(ip)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			logger.FatalLog(fmt.Sprintf("Failed to read stdin: %v", err))
+		}
+	} else {
+		for _, ip := range fs.Args() {
+			lookup(ip)
+		}
+	}
+	if failed {
+		out.Flush()
+		os.Exit(1)
+	}
+}
+
+// updateCommand go-geoip update ; 仅下载并校验数据库, 适合在 cron 中运行.
+// 以打开数据库作为校验, 不构建 ASN 索引, 也不加载安全列表等 Provider.
+// 任一数据库下载或校验失败时以状态码 1 退出
+func updateCommand(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	_ = fs.Parse(args)
+	setupTracing()
+	err := loadDatabases(cliOptions(false))
+	shutdownTracing()
+	if err != nil {
+		logger.SysError(fmt.Sprintf("Database update failed: %v", err))
+		os.Exit(1)
+	}
+	logger.SysLog("Databases updated")
+}
+
+// infoCommand go-geoip info ; 输出工作目录中各数据库文件的元数据
+func infoCommand(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	files := []struct{ name, file string }{
+		{"city", common.CityDBFile},
+		{"asn", common.AsnDBFile},
+		{"cn", common.CnDBFile},
+		{"anonymous", common.AnonymousDBFile},
+	}
+	var infos []model.DatabaseInfo
+	for _, f := range files {
+		infos = append(infos, geoip.ReadDatabaseInfo(f.name, f.file))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(infos)
+}
+
+// exportCommand go-geoip export [--format csv|jsonl] [--output file]
+func exportCommand(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "output format: csv or jsonl")
+	output := fs.String("output", "-", "output file, - for stdout")
+	_ = fs.Parse(args)
+
+	ensureDatabases(cliOptions(false))
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			logger.FatalLog(fmt.Sprintf("Failed to create %s: %v", *output, err))
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := engine.Export(context.Background(), w, *format); err != nil {
+		logger.FatalLog(fmt.Sprintf("Export failed: %v", err))
+	}
+}
+
+// cliOptions 子命令不需要 ASN 索引; 仅查询类命令加载安全列表等 Provider
+func cliOptions(providers bool) geoip.Options {
+	opts := engineOptions()
+	opts.ASNIndex = false
+	if !providers {
+		opts.Providers = nil
+	}
+	return opts
+}
+
+// ensureDatabases 仅下载工作目录中缺失的数据库文件, 然后打开
+func ensureDatabases(opts geoip.Options) {
+	files := map[string]string{
+		common.CityDBFile: getCityDBURL(),
+		common.AsnDBFile:  getAsnDBURL(),
+		common.CnDBFile:   getCnDBURL(),
+	}
+	if config.AnonymousDBRemoteUrl != "" {
+		files[common.AnonymousDBFile] = config.AnonymousDBRemoteUrl
+	}
+	for filename, url := range files {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			_ = downloadAndSave(context.Background(), filename, url)
+		}
+	}
+	_ = openDatabases(context.Background(), opts)
+}
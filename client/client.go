@@ -0,0 +1,275 @@
+// Package client is a typed Go client for the go-geoip HTTP API.
+//
+//	c := client.New("http://localhost:7099", client.WithAPIKey("123456"), client.WithCache(10000))
+//	info, err := c.Lookup(ctx, "8.8.8.8")
+//	if errors.Is(err, common.ErrNotFound) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-geoip/common"
+	"go-geoip/common/lru"
+	"go-geoip/model"
+)
+
+// Client 并发安全, 应在多个 goroutine 间复用
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	cache      *lru.Cache[string, json.RawMessage]
+}
+
+type Option func(*Client)
+
+// WithAPIKey 以 Authorization: Bearer <key> 发送
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient 替换默认的 http.Client, 如需自定义超时或传输层
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetry 遇到 429/503 时最多重试 maxRetries 次, 退避从 backoff 开始指数增长; 服务端返回 Retry-After 时以其为准, 最长等待 10 秒
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithCache 在本地以 LRU 缓存 Lookup 结果
+func WithCache(size int) Option {
+	return func(c *Client) { c.cache = lru.New[string, json.RawMessage](size) }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// LookupOption 对应 /ip/{ip} 的查询参数
+type LookupOption func(url.Values)
+
+// WithRDNS 返回 PTR 反向解析结果
+func WithRDNS() LookupOption {
+	return func(q url.Values) { q.Set("rdns", "1") }
+}
+
+// WithStrict 所有数据库均未命中时返回 common.ErrNotFound
+func WithStrict() LookupOption {
+	return func(q url.Values) { q.Set("strict", "1") }
+}
+
+// Lookup 查询指定 IP
+func (c *Client) Lookup(ctx context.Context, ip string, opts ...LookupOption) (*model.IPInfoResponse, error) {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+	path := "/ip/" + url.PathEscape(ip)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	// 缓存响应的 JSON, 每次命中都解码出新的结果, 调用方修改返回值不影响缓存
+	if c.cache != nil {
+		if data, ok := c.cache.Get(path); ok {
+			return decodeInfo(data)
+		}
+	}
+	var data json.RawMessage
+	if err := c.do(ctx, http.MethodGet, path, nil, &data); err != nil {
+		return nil, err
+	}
+	info, err := decodeInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		c.cache.Add(path, data)
+	}
+	return info, nil
+}
+
+func decodeInfo(data json.RawMessage) (*model.IPInfoResponse, error) {
+	var info model.IPInfoResponse
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Self 查询调用方自身的出口 IP
+func (c *Client) Self(ctx context.Context) (*model.IPInfoResponse, error) {
+	var info model.IPInfoResponse
+	if err := c.do(ctx, http.MethodGet, "/ip", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// LookupBatch 批量查询, 单个 IP 的失败记录在对应 BatchResult.Error 中
+func (c *Client) LookupBatch(ctx context.Context, ips []string, opts ...LookupOption) ([]model.BatchResult, error) {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+	path := "/ip/batch"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	body, err := json.Marshal(model.BatchRequest{IPs: ips})
+	if err != nil {
+		return nil, err
+	}
+	var resp model.BatchResponse
+	if err := c.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Info 返回服务版本及数据库元数据
+func (c *Client) Info(ctx context.Context) (*model.ServiceInfo, error) {
+	var info model.ServiceInfo
+	if err := c.do(ctx, http.MethodGet, "/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			var env envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			return json.Unmarshal(env.Data, out)
+		}
+
+		apiErr := decodeError(resp, data)
+		if !retryable(resp.StatusCode) || attempt >= c.maxRetries {
+			return apiErr
+		}
+		if err := sleep(ctx, c.retryDelay(attempt, resp.Header.Get("Retry-After"))); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpClient.Do(req)
+}
+
+// decodeError 同时兼容普通响应体与 ERROR_FORMAT=problem 时的 problem+json
+func decodeError(resp *http.Response, data []byte) *common.APIError {
+	apiErr := &common.APIError{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+		var problem common.ProblemDetails
+		if json.Unmarshal(data, &problem) == nil {
+			apiErr.Code = problem.Code
+			apiErr.Key = strings.TrimPrefix(problem.Type, "urn:go-geoip:error:")
+			apiErr.Message = problem.Title
+			apiErr.Detail = problem.Detail
+		}
+		return apiErr
+	}
+	var env envelope
+	if json.Unmarshal(data, &env) == nil && env.Error != "" {
+		apiErr.Code = env.Code
+		apiErr.Key = env.Error
+		apiErr.Message = env.Message
+	}
+	return apiErr
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+func (c *Client) retryDelay(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		// 过大的 Retry-After 按 maxBackoff 等待, 先比较秒数避免乘法溢出
+		if time.Duration(seconds) > c.maxBackoff/time.Second {
+			return c.maxBackoff
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	delay := c.backoff << attempt
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	// 加入最多 50% 的随机抖动, 避免多个客户端同时重试
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
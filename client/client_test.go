@@ -0,0 +1,296 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/client"
+	"go-geoip/common"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+	"go-geoip/controller"
+	"go-geoip/geoip/geoiptest"
+	"go-geoip/middleware"
+	"go-geoip/router"
+)
+
+var (
+	// apiSecret 测试服务中唯一密钥的明文, 由 TestMain 创建
+	apiSecret string
+	// requests 测试服务收到的请求数, 用于检查缓存与重试
+	requests atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	// 密钥与用量账本只保存在内存中
+	config.APIKeysFile = ""
+	config.UsageFile = ""
+	os.Exit(m.Run())
+}
+
+// newServer 以 router.SetRouter 构建与线上相同的路由, 引擎使用 geoiptest 的数据库
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	controller.SetEngine(geoiptest.NewEngine(t))
+
+	store, err := apikey.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiSecret == "" {
+		_, apiSecret, err = store.Create(apikey.Spec{Label: "client-test", Scopes: []string{apikey.ScopeAll}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine := gin.New()
+	engine.Use(middleware.RequestId())
+	router.SetRouter(engine)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		engine.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLookup(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithAPIKey(apiSecret))
+
+	info, err := c.Lookup(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CountryCode != "US" || info.ASN != 15169 || info.AS != "GOOGLE" {
+		t.Errorf("Lookup(8.8.8.8) = %+v", info)
+	}
+
+	_, err = c.Lookup(context.Background(), "not-an-ip")
+	if !errors.Is(err, common.ErrInvalidIP) {
+		t.Errorf("Lookup(not-an-ip) error = %v, want invalid_ip", err)
+	}
+}
+
+func TestSelf(t *testing.T) {
+	server := newServer(t)
+	// 测试服务的客户端地址为回环地址, 以 X-Forwarded-For 模拟经过代理的公网出口
+	httpClient := &http.Client{Transport: forwardedFor{"1.1.1.1"}}
+	c := client.New(server.URL, client.WithHTTPClient(httpClient))
+
+	info, err := c.Self(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IP != "1.1.1.1" || info.CountryCode != "AU" {
+		t.Errorf("Self() = %+v", info)
+	}
+}
+
+func TestLookupBatch(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithAPIKey(apiSecret))
+
+	results, err := c.LookupBatch(context.Background(), []string{"8.8.8.8", "bogus", "1.0.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Data == nil || results[0].Data.CountryCode != "US" {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if results[1].Error != common.ErrInvalidIP.Key {
+		t.Errorf("results[1].Error = %q, want %q", results[1].Error, common.ErrInvalidIP.Key)
+	}
+	if results[2].Data == nil || results[2].Data.Province != "福建省" {
+		t.Errorf("results[2] = %+v", results[2])
+	}
+}
+
+func TestInfo(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithAPIKey(apiSecret))
+
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != common.Version {
+		t.Errorf("Version = %q, want %q", info.Version, common.Version)
+	}
+	loaded := map[string]bool{}
+	for _, db := range info.Databases {
+		loaded[db.Name] = db.Loaded
+	}
+	for _, name := range []string{"city", "asn", "cn", "anonymous"} {
+		if !loaded[name] {
+			t.Errorf("database %s not loaded: %+v", name, info.Databases)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	server := newServer(t)
+	ctx := context.Background()
+
+	if _, err := client.New(server.URL).Lookup(ctx, "8.8.8.8"); !errors.Is(err, common.ErrUnauthorized) {
+		t.Errorf("without key: error = %v, want unauthorized", err)
+	}
+	if _, err := client.New(server.URL, client.WithAPIKey("wrong")).Lookup(ctx, "8.8.8.8"); !errors.Is(err, common.ErrUnauthorized) {
+		t.Errorf("wrong key: error = %v, want unauthorized", err)
+	}
+	if _, err := client.New(server.URL, client.WithAPIKey(apiSecret)).Lookup(ctx, "8.8.8.8"); err != nil {
+		t.Errorf("bearer key: %v", err)
+	}
+
+	// 服务端同样接受 X-API-Key 请求头
+	httpClient := &http.Client{Transport: apiKeyHeader{apiSecret}}
+	if _, err := client.New(server.URL, client.WithHTTPClient(httpClient)).Lookup(ctx, "8.8.8.8"); err != nil {
+		t.Errorf("X-API-Key header: %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithAPIKey(apiSecret), client.WithCache(1))
+	ctx := context.Background()
+
+	before := requests.Load()
+	for range 3 {
+		if _, err := c.Lookup(ctx, "8.8.8.8"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load() - before; n != 1 {
+		t.Errorf("repeated lookups sent %d requests, want 1", n)
+	}
+
+	// 容量为 1, 查询另一个 IP 后 8.8.8.8 被淘汰
+	if _, err := c.Lookup(ctx, "1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Lookup(ctx, "8.8.8.8"); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load() - before; n != 3 {
+		t.Errorf("sent %d requests after eviction, want 3", n)
+	}
+}
+
+// 修改 Lookup 返回的结果不影响缓存中的数据
+func TestCacheReturnsCopies(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithAPIKey(apiSecret), client.WithCache(10))
+	ctx := context.Background()
+
+	first, err := c.Lookup(ctx, "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Latitude == nil || len(first.Subdivisions) == 0 {
+		t.Fatalf("test record has no location or subdivisions: %+v", first)
+	}
+	first.CountryCode = "XX"
+	*first.Latitude = -1
+	first.Subdivisions[0] = "modified"
+
+	before := requests.Load()
+	second, err := c.Lookup(ctx, "8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != before {
+		t.Fatal("second lookup was not served from the cache")
+	}
+	if second == first || second.CountryCode != "US" || *second.Latitude == -1 || second.Subdivisions[0] == "modified" {
+		t.Errorf("cached result was modified through the returned value: %+v", second)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		minElapsed time.Duration
+	}{
+		{"429 with Retry-After", http.StatusTooManyRequests, "1", time.Second},
+		{"503 with backoff", http.StatusServiceUnavailable, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte(`{"code":200,"data":{"ip":"8.8.8.8","country_code":"US"}}`))
+			}))
+			defer server.Close()
+
+			c := client.New(server.URL, client.WithRetry(2, time.Millisecond))
+			start := time.Now()
+			info, err := c.Lookup(context.Background(), "8.8.8.8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.CountryCode != "US" || calls.Load() != 2 {
+				t.Errorf("info = %+v after %d calls", info, calls.Load())
+			}
+			if elapsed := time.Since(start); elapsed < tt.minElapsed {
+				t.Errorf("retried after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"code":42901,"error":"rate_limited","message":"slow down"}`))
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL, client.WithRetry(2, time.Millisecond))
+	_, err := c.Lookup(context.Background(), "8.8.8.8")
+	if !errors.Is(err, common.ErrRateLimited) {
+		t.Errorf("error = %v, want rate_limited", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("sent %d requests, want 1 + 2 retries", calls.Load())
+	}
+}
+
+type forwardedFor struct{ ip string }
+
+func (f forwardedFor) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Forwarded-For", f.ip)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type apiKeyHeader struct{ key string }
+
+func (a apiKeyHeader) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(middleware.APIKeyHeader, a.key)
+	return http.DefaultTransport.RoundTrip(req)
+}
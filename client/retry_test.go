@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	c := New("http://localhost", WithRetry(3, 100*time.Millisecond))
+	tests := []struct {
+		retryAfter string
+		attempt    int
+		min, max   time.Duration
+	}{
+		{"0", 0, 0, 0},
+		{"1", 0, time.Second, time.Second},
+		{"10", 0, 10 * time.Second, 10 * time.Second},
+		{"11", 0, c.maxBackoff, c.maxBackoff},
+		{"3600", 0, c.maxBackoff, c.maxBackoff},
+		{"9223372036854775807", 0, c.maxBackoff, c.maxBackoff}, // 乘以 time.Second 会溢出
+		// 无法解析时按指数退避并加入抖动
+		{"", 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"-1", 2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{"", 20, c.maxBackoff / 2, c.maxBackoff},
+	}
+	for _, tt := range tests {
+		for range 10 {
+			if d := c.retryDelay(tt.attempt, tt.retryAfter); d < tt.min || d > tt.max {
+				t.Errorf("retryDelay(%d, %q) = %v, want %v-%v", tt.attempt, tt.retryAfter, d, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package apikey
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go-geoip/common/config"
+	logger "go-geoip/common/loggger"
+)
+
+// MonthFormat 账本按 UTC 自然月汇总
+const MonthFormat = "2006-01"
+
+// ledgerFlushInterval 账本写回文件的间隔, 进程异常退出时最多丢失这段时间内的记录
+const ledgerFlushInterval = 30 * time.Second
+
+// Usage 一个密钥在一个月内的用量; Units 为计费单位, 批量查询按 IP 数计
+type Usage struct {
+	Requests int64            `json:"requests"`
+	Units    int64            `json:"units"`
+	Routes   map[string]int64 `json:"routes"`
+}
+
+// Ledger 每个密钥 (含 JWT/mTLS/签名请求的身份) 按月累计的用量, 定期写回 JSON 文件; path 为空时仅保存在内存中
+type Ledger struct {
+	path string
+
+	mutex  sync.Mutex
+	months map[string]map[string]*Usage // 月份 -> 密钥 ID -> 用量
+	dirty  bool
+}
+
+var (
+	defaultLedger    *Ledger
+	defaultLedgerErr error
+	ledgerOnce       sync.Once
+)
+
+// DefaultLedger 返回按 USAGE_FILE 打开的单例, 并在后台定期写回
+func DefaultLedger() (*Ledger, error) {
+	ledgerOnce.Do(func() {
+		defaultLedger, defaultLedgerErr = OpenLedger(config.UsageFile)
+		if defaultLedgerErr == nil {
+			go defaultLedger.flushLoop()
+		}
+	})
+	return defaultLedger, defaultLedgerErr
+}
+
+// OpenLedger 文件不存在时返回空账本
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, months: map[string]map[string]*Usage{}}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.months); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record 计入一次请求及其消耗的计费单位
+func (l *Ledger) Record(keyID, route string, units int) {
+	month := time.Now().UTC().Format(MonthFormat)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	keys, ok := l.months[month]
+	if !ok {
+		keys = map[string]*Usage{}
+		l.months[month] = keys
+	}
+	usage, ok := keys[keyID]
+	if !ok {
+		usage = &Usage{Routes: map[string]int64{}}
+		keys[keyID] = usage
+	}
+	usage.Requests++
+	usage.Units += int64(units)
+	usage.Routes[route] += int64(units)
+	l.dirty = true
+}
+
+// Get 返回密钥在指定月份 (MonthFormat) 的用量, 没有记录时为零值
+func (l *Ledger) Get(keyID, month string) Usage {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	usage := Usage{Routes: map[string]int64{}}
+	if u, ok := l.months[month][keyID]; ok {
+		usage.Requests, usage.Units = u.Requests, u.Units
+		for route, units := range u.Routes {
+			usage.Routes[route] = units
+		}
+	}
+	return usage
+}
+
+// Flush 有新记录时写回文件, 先写临时文件再重命名
+func (l *Ledger) Flush() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.path == "" || !l.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.months, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+func (l *Ledger) flushLoop() {
+	for {
+		time.Sleep(ledgerFlushInterval)
+		if err := l.Flush(); err != nil {
+			logger.SysError(fmt.Sprintf("failed to save usage ledger: %v", err))
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-geoip/common/config"
+)
+
+// 权限范围, 每个需要鉴权的路由对应一个
+const (
+	ScopeLookup  = "lookup"
+	ScopeBatch   = "batch"
+	ScopeNetwork = "network"
+	ScopeASN     = "asn"
+	ScopeCountry = "country"
+	ScopeExport  = "export"
+	ScopeAdmin   = "admin"
+	// ScopeAll 代表除 admin 以外的全部权限
+	ScopeAll = "*"
+)
+
+// Scopes 可分配的全部权限范围
+var Scopes = []string{ScopeLookup, ScopeBatch, ScopeNetwork, ScopeASN, ScopeCountry, ScopeExport, ScopeAdmin, ScopeAll}
+
+// 密钥格式为 <secretPrefix><id>.<随机部分>, 便于按 ID 找到记录后再比较哈希
+const secretPrefix = "gk_"
+
+var (
+	ErrInvalidKey    = errors.New("invalid API key")
+	ErrExpired       = errors.New("API key expired")
+	ErrRevoked       = errors.New("API key revoked")
+	ErrKeyNotFound   = errors.New("API key not found")
+	ErrQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+// Key 一条密钥记录; 只保存密钥的 SHA-256, 明文仅在创建与轮换时返回一次
+type Key struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	SecretHash string     `json:"secret_hash"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`  // 每个限流窗口的请求数, 0 使用 REQUEST_RATE_LIMIT
+	DailyQuota int        `json:"daily_quota"` // 每个 UTC 日的计费单位, 0 为不限
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope ScopeAll 不包含 admin, 管理权限必须显式授予
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || (s == ScopeAll && scope != ScopeAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active 未吊销且未过期
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Spec 创建密钥时的可选属性
+type Spec struct {
+	Label      string
+	Scopes     []string
+	RateLimit  int
+	DailyQuota int
+	ExpiresAt  *time.Time
+}
+
+type dailyUsage struct {
+	day   string
+	count int
+}
+
+// Store 密钥存储, 修改后整体写回 JSON 文件; path 为空时仅保存在内存中
+type Store struct {
+	path string
+
+	mutex sync.RWMutex
+	keys  map[string]*Key
+	usage map[string]*dailyUsage
+}
+
+var (
+	defaultStore *Store
+	defaultErr   error
+	once         sync.Once
+)
+
+// Default 返回按 API_KEYS_FILE 打开的单例
+func Default() (*Store, error) {
+	once.Do(func() {
+		defaultStore, defaultErr = Open(config.APIKeysFile)
+	})
+	return defaultStore, defaultErr
+}
+
+// Open 文件不存在时返回空的 Store, 首次写入时创建
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string]*Key{}, usage: map[string]*dailyUsage{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+	return s, nil
+}
+
+// Enabled 创建过密钥 (含已吊销, 避免吊销最后一个密钥后服务变为公开) 或配置了 API_SECRET 时要求鉴权
+func (s *Store) Enabled() bool {
+	if config.ApiSecret != "" {
+		return true
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.keys) > 0
+}
+
+// Authenticate 校验密钥, 哈希比较为常数时间; API_SECRET 与 ADMIN_SECRET 中的密钥作为内置密钥
+func (s *Store) Authenticate(secret string) (*Key, error) {
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+	if key := builtinKey(secret); key != nil {
+		return key, nil
+	}
+
+	id, ok := parseID(secret)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	s.mutex.RLock()
+	key, ok := s.keys[id]
+	var k Key
+	if ok {
+		k = *key
+	}
+	s.mutex.RUnlock()
+
+	if !ok || !equal(hashSecret(secret), k.SecretHash) {
+		return nil, ErrInvalidKey
+	}
+	now := time.Now()
+	if k.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return &k, nil
+}
+
+// Consume 按 cost 计入当日用量, 超出当日配额时返回 ErrQuotaExceeded 且不计入
+func (s *Store) Consume(key *Key, cost int) error {
+	if key.DailyQuota <= 0 {
+		return nil
+	}
+	day := time.Now().UTC().Format(time.DateOnly)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	usage, ok := s.usage[key.ID]
+	if !ok || usage.day != day {
+		usage = &dailyUsage{day: day}
+		s.usage[key.ID] = usage
+	}
+	if usage.count+cost > key.DailyQuota {
+		return ErrQuotaExceeded
+	}
+	usage.count += cost
+	return nil
+}
+
+// Refund 退还 Consume 计入的用量, 用于请求最终未成功 (如权限不足或参数错误) 的情况
+func (s *Store) Refund(key *Key, cost int) {
+	if key.DailyQuota <= 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if usage, ok := s.usage[key.ID]; ok && usage.day == time.Now().UTC().Format(time.DateOnly) {
+		usage.count = max(usage.count-cost, 0)
+	}
+}
+
+// DailyUsage 返回密钥当日 (UTC) 已使用的计费单位
+func (s *Store) DailyUsage(id string) int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if usage, ok := s.usage[id]; ok && usage.day == time.Now().UTC().Format(time.DateOnly) {
+		return usage.count
+	}
+	return 0
+}
+
+// List 按创建时间返回全部密钥 (含已吊销)
+func (s *Store) List() []Key {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	keys := make([]Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, *k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Create 生成新密钥, 返回记录与明文密钥
+func (s *Store) Create(spec Spec) (*Key, string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := newSecret(id)
+	if err != nil {
+		return nil, "", err
+	}
+	scopes := spec.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{ScopeAll}
+	}
+	key := &Key{
+		ID:         id,
+		Label:      spec.Label,
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+		RateLimit:  spec.RateLimit,
+		DailyQuota: spec.DailyQuota,
+		CreatedAt:  time.Now().UTC(),
+		ExpiresAt:  spec.ExpiresAt,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[id] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return nil, "", err
+	}
+	k := *key
+	return &k, secret, nil
+}
+
+// Rotate 为已有密钥生成新的明文, 旧密钥立即失效
+func (s *Store) Rotate(id string) (*Key, string, error) {
+	secret, err := newSecret(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, "", ErrKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil, "", ErrRevoked
+	}
+	previous := *key
+	now := time.Now().UTC()
+	key.SecretHash = hashSecret(secret)
+	key.RotatedAt = &now
+	if err := s.save(); err != nil {
+		*key = previous
+		return nil, "", err
+	}
+	k := *key
+	return &k, secret, nil
+}
+
+// Revoke 吊销密钥, 记录保留以便审计
+func (s *Store) Revoke(id string) (*Key, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		if err := s.save(); err != nil {
+			key.RevokedAt = nil
+			return nil, err
+		}
+	}
+	k := *key
+	return &k, nil
+}
+
+// save 先写临时文件再重命名, 调用方需持有写锁
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// builtinKey API_SECRET 中的密钥拥有除 admin 外的全部权限, ADMIN_SECRET 拥有全部权限
+func builtinKey(secret string) *Key {
+	if config.AdminSecret != "" && equal(secret, config.AdminSecret) {
+		return &Key{ID: "admin", Label: "ADMIN_SECRET", Scopes: []string{ScopeAll, ScopeAdmin}}
+	}
+	if config.ApiSecret != "" {
+		for _, s := range config.ApiSecrets {
+			if s != "" && equal(secret, s) {
+				return &Key{ID: "env", Label: "API_SECRET", Scopes: []string{ScopeAll}}
+			}
+		}
+	}
+	return nil
+}
+
+func parseID(secret string) (string, bool) {
+	rest, ok := strings.CutPrefix(secret, secretPrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, ".")
+	return id, ok && id != ""
+}
+
+func newSecret(id string) (string, error) {
+	random, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + id + "." + random, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret 密钥本身是 192 位随机数, 不需要慢哈希
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
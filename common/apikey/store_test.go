@@ -0,0 +1,218 @@
+package apikey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-geoip/common/config"
+)
+
+func TestAuthenticate(t *testing.T) {
+	defer func(apiSecret string, apiSecrets []string, adminSecret string) {
+		config.ApiSecret, config.ApiSecrets, config.AdminSecret = apiSecret, apiSecrets, adminSecret
+	}(config.ApiSecret, config.ApiSecrets, config.AdminSecret)
+	config.ApiSecret, config.ApiSecrets, config.AdminSecret = "env-1,env-2", []string{"env-1", "env-2"}, "root"
+
+	s, _ := Open("")
+	_, secret, err := s.Create(Spec{Label: "web", Scopes: []string{ScopeLookup}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedSecret, _ := s.Create(Spec{})
+	if _, err := s.Revoke(revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	_, expiredSecret, _ := s.Create(Spec{ExpiresAt: &past})
+	future := time.Now().Add(time.Hour)
+	_, activeSecret, _ := s.Create(Spec{ExpiresAt: &future})
+	rotated, oldSecret, _ := s.Create(Spec{})
+	_, newSecret, err := s.Rotate(rotated.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := parseID(secret)
+	tests := []struct {
+		name    string
+		secret  string
+		wantID  string
+		wantErr error
+	}{
+		{"valid", secret, id, nil},
+		{"same id, wrong random part", secretPrefix + id + "." + strings.Repeat("0", 48), "", ErrInvalidKey},
+		{"unknown id", secretPrefix + "ffffffffffffffff." + strings.Repeat("0", 48), "", ErrInvalidKey},
+		{"missing prefix", strings.TrimPrefix(secret, secretPrefix), "", ErrInvalidKey},
+		{"missing random part", secretPrefix + id, "", ErrInvalidKey},
+		{"empty", "", "", ErrInvalidKey},
+		{"revoked", revokedSecret, "", ErrRevoked},
+		{"expired", expiredSecret, "", ErrExpired},
+		{"not yet expired", activeSecret, "", nil},
+		{"old secret after rotation", oldSecret, "", ErrInvalidKey},
+		{"new secret after rotation", newSecret, rotated.ID, nil},
+		{"API_SECRET", "env-2", "env", nil},
+		{"ADMIN_SECRET", "root", "admin", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := s.Authenticate(tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantID != "" && key.ID != tt.wantID {
+				t.Errorf("key ID = %q, want %q", key.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{[]string{ScopeLookup}, ScopeLookup, true},
+		{[]string{ScopeLookup}, ScopeBatch, false},
+		{[]string{ScopeAll}, ScopeExport, true},
+		{[]string{ScopeAll}, ScopeAdmin, false}, // * 不包含 admin
+		{[]string{ScopeAll, ScopeAdmin}, ScopeAdmin, true},
+		{[]string{ScopeAdmin}, ScopeLookup, false},
+		{nil, ScopeLookup, false},
+	}
+	for _, tt := range tests {
+		key := &Key{Scopes: tt.scopes}
+		if got := key.HasScope(tt.scope); got != tt.want {
+			t.Errorf("%v.HasScope(%q) = %v, want %v", tt.scopes, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	s, _ := Open("")
+	key, _, _ := s.Create(Spec{})
+
+	if _, _, err := s.Rotate("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Rotate(missing) error = %v", err)
+	}
+	if _, err := s.Revoke("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Revoke(missing) error = %v", err)
+	}
+
+	first, err := s.Revoke(key.ID)
+	if err != nil || first.RevokedAt == nil {
+		t.Fatalf("Revoke = %+v, %v", first, err)
+	}
+	// 重复吊销保留首次吊销时间
+	second, err := s.Revoke(key.ID)
+	if err != nil || !second.RevokedAt.Equal(*first.RevokedAt) {
+		t.Errorf("second Revoke = %+v, %v", second, err)
+	}
+	if _, _, err := s.Rotate(key.ID); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Rotate(revoked) error = %v, want ErrRevoked", err)
+	}
+	// 吊销后仍要求鉴权, 避免吊销最后一个密钥后服务变为公开
+	if !s.Enabled() {
+		t.Error("store with only revoked keys is not enabled")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	s, _ := Open("")
+	key := &Key{ID: "k", DailyQuota: 5}
+
+	steps := []struct {
+		op       string
+		cost     int
+		wantErr  error
+		wantUsed int
+	}{
+		{"consume", 3, nil, 3},
+		{"consume", 2, nil, 5}, // 恰好用完
+		{"consume", 1, ErrQuotaExceeded, 5},
+		{"refund", 2, nil, 3},
+		{"consume", 3, ErrQuotaExceeded, 3}, // 超出时不计入
+		{"consume", 2, nil, 5},
+		{"refund", 10, nil, 0}, // 不低于 0
+	}
+	for i, step := range steps {
+		var err error
+		if step.op == "consume" {
+			err = s.Consume(key, step.cost)
+		} else {
+			s.Refund(key, step.cost)
+		}
+		if !errors.Is(err, step.wantErr) || s.DailyUsage(key.ID) != step.wantUsed {
+			t.Fatalf("step %d %s(%d): error %v, used %d; want %v, %d", i+1, step.op, step.cost, err, s.DailyUsage(key.ID), step.wantErr, step.wantUsed)
+		}
+	}
+
+	// 未设置配额时不限且不计数
+	unlimited := &Key{ID: "u"}
+	if err := s.Consume(unlimited, 1_000_000); err != nil || s.DailyUsage("u") != 0 {
+		t.Errorf("unlimited key: %v, used %d", err, s.DailyUsage("u"))
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-keys.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled() {
+		t.Error("empty store is enabled")
+	}
+
+	web, webSecret, _ := s.Create(Spec{Label: "web", Scopes: []string{ScopeLookup, ScopeBatch}, RateLimit: 600, DailyQuota: 1000})
+	rotated, _, _ := s.Create(Spec{Label: "rotated"})
+	_, rotatedSecret, _ := s.Rotate(rotated.ID)
+	revoked, revokedSecret, _ := s.Create(Spec{Label: "revoked"})
+	_, _ = s.Revoke(revoked.ID)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 文件中只保存哈希
+	for _, secret := range []string{webSecret, rotatedSecret, revokedSecret} {
+		if strings.Contains(string(data), secret) {
+			t.Error("plaintext secret written to the key file")
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := reopened.List()
+	if len(keys) != 3 || keys[0].ID != web.ID || keys[1].RotatedAt == nil || keys[2].RevokedAt == nil {
+		t.Fatalf("reloaded keys = %+v", keys)
+	}
+	if keys[0].Label != "web" || keys[0].RateLimit != 600 || keys[0].DailyQuota != 1000 || !keys[0].HasScope(ScopeBatch) {
+		t.Errorf("reloaded key = %+v", keys[0])
+	}
+	if key, err := reopened.Authenticate(webSecret); err != nil || key.ID != web.ID {
+		t.Errorf("Authenticate after reload: %+v, %v", key, err)
+	}
+	if _, err := reopened.Authenticate(rotatedSecret); err != nil {
+		t.Errorf("rotated secret after reload: %v", err)
+	}
+	if _, err := reopened.Authenticate(revokedSecret); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked secret after reload: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open accepted a corrupted key file")
+	}
+}
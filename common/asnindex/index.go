@@ -0,0 +1,104 @@
+package asnindex
+
+import (
+	"math"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go-geoip/model"
+)
+
+// Index ASN 到前缀列表的反向索引, 构建完成后只读
+type Index struct {
+	byNumber map[uint]*model.ASNInfo
+	sorted   []*model.ASNInfo
+}
+
+// Build 遍历 ASN 数据库的所有网段构建索引
+func Build(reader *maxminddb.Reader) (*Index, error) {
+	index := &Index{byNumber: map[uint]*model.ASNInfo{}}
+
+	networks := reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var asn model.ASN
+		network, err := networks.Network(&asn)
+		if err != nil {
+			return nil, err
+		}
+		if asn.Number == 0 {
+			continue
+		}
+		info, ok := index.byNumber[asn.Number]
+		if !ok {
+			info = &model.ASNInfo{
+				Number:       asn.Number,
+				Organization: asn.Organization,
+				IPv4Prefixes: []string{},
+				IPv6Prefixes: []string{},
+			}
+			index.byNumber[asn.Number] = info
+			index.sorted = append(index.sorted, info)
+		}
+		addPrefix(info, network.String())
+	}
+	if err := networks.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(index.sorted, func(i, j int) bool {
+		return index.sorted[i].Number < index.sorted[j].Number
+	})
+	return index, nil
+}
+
+func addPrefix(info *model.ASNInfo, cidr string) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return
+	}
+	if prefix.Addr().Is4() {
+		info.IPv4Prefixes = append(info.IPv4Prefixes, cidr)
+		info.IPv4Addresses += 1 << (32 - prefix.Bits())
+		return
+	}
+	info.IPv6Prefixes = append(info.IPv6Prefixes, cidr)
+	// IPv6 以 /64 为单位计数, 超出 uint64 时饱和
+	if bits := prefix.Bits(); bits <= 64 {
+		var count uint64 = math.MaxUint64
+		if bits > 0 {
+			count = 1 << (64 - bits)
+		}
+		if info.IPv6Slash64s > math.MaxUint64-count {
+			info.IPv6Slash64s = math.MaxUint64
+		} else {
+			info.IPv6Slash64s += count
+		}
+	} else if info.IPv6Slash64s < math.MaxUint64 {
+		info.IPv6Slash64s++
+	}
+}
+
+// Get 按 AS 号查询
+func (idx *Index) Get(number uint) (*model.ASNInfo, bool) {
+	info, ok := idx.byNumber[number]
+	return info, ok
+}
+
+// Search 按组织名称子串 (不区分大小写) 搜索, 结果按 AS 号排序
+func (idx *Index) Search(org string) []*model.ASNInfo {
+	org = strings.ToLower(org)
+	var result []*model.ASNInfo
+	for _, info := range idx.sorted {
+		if strings.Contains(strings.ToLower(info.Organization), org) {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// Len 返回 AS 数量
+func (idx *Index) Len() int {
+	return len(idx.sorted)
+}
@@ -0,0 +1,124 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// checkInterval 两次检查证书文件修改时间的最小间隔
+const checkInterval = 10 * time.Second
+
+// Reloader 按文件修改时间自动重新加载服务端证书与客户端 CA, 替换证书无需重启;
+// 新文件无法解析时继续使用旧的证书
+type Reloader struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+
+	mutex     sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time
+	checked   time.Time
+}
+
+// New caFile 为空时不校验客户端证书; requireClientCert 为 false 时客户端证书可选, 提供时仍需通过校验
+func New(certFile, keyFile, caFile string, requireClientCert bool) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile, clientAuth: tls.NoClientCert}
+	if caFile != "" {
+		r.clientAuth = tls.VerifyClientCertIfGiven
+		if requireClientCert {
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	if err := r.load(r.modTimesNow()); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+// TLSConfig 返回的配置在每次握手时取当前的证书与 CA
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    clientCAs,
+			}, nil
+		},
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if time.Since(r.checked) >= checkInterval {
+		r.checked = time.Now()
+		if modTimes := r.modTimesNow(); modTimes != r.modTimes {
+			// 加载失败时保留旧证书, 下次检查时重试
+			_ = r.load(modTimes)
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+// load 调用方需持有锁 (New 除外)
+func (r *Reloader) load(modTimes [3]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: %w", r.caFile, ErrNoCertificates)
+		}
+	}
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+func (r *Reloader) modTimesNow() [3]time.Time {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		if stat, err := os.Stat(path); err == nil {
+			modTimes[i] = stat.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// Identity 返回客户端证书对应的身份: 优先 URI SAN (如 SPIFFE ID), 其次 DNS SAN, 最后 Subject CN
+func Identity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
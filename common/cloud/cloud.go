@@ -0,0 +1,155 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"go-geoip/common"
+	"go-geoip/common/config"
+	"go-geoip/common/iptrie"
+	logger "go-geoip/common/loggger"
+	"go-geoip/model"
+)
+
+type providerRanges struct {
+	trie  *iptrie.Trie[*model.Cloud]
+	count int
+}
+
+// Ranges 云厂商公布的 IP 段, 每个厂商一棵前缀树, 作为 geoip.Provider 使用
+type Ranges struct {
+	sources []string
+	current atomic.Pointer[map[string]*providerRanges]
+}
+
+// NewRanges sources 格式为 provider=URL或路径, 需调用 Reload 后才有数据
+func NewRanges(sources []string) *Ranges {
+	return &Ranges{sources: sources}
+}
+
+// FromConfig 按 CLOUD_RANGE_SOURCES 创建
+func FromConfig() *Ranges {
+	return NewRanges(config.CloudRangeSources)
+}
+
+// Enabled 是否配置了任一来源
+func (r *Ranges) Enabled() bool {
+	return len(r.sources) > 0
+}
+
+func (r *Ranges) Name() string {
+	return "cloud_ranges"
+}
+
+// Enrich 设置 info.Cloud; 云厂商 IP 段同时视为托管地址, 没有 Anonymous-IP 数据库与安全列表时新建 info.Security
+func (r *Ranges) Enrich(_ context.Context, addr netip.Addr, info *model.IPInfoResponse) {
+	info.Cloud = r.Lookup(addr)
+	if info.Cloud == nil {
+		return
+	}
+	if info.Security == nil {
+		info.Security = &model.Security{Sources: []string{}}
+	}
+	info.Security.IsHosting = true
+	info.Security.Sources = append(info.Security.Sources, r.Name())
+}
+
+// Lookup 在所有云厂商中做最长前缀匹配, 未命中返回 nil
+func (r *Ranges) Lookup(addr netip.Addr) *model.Cloud {
+	providers := r.current.Load()
+	if providers == nil {
+		return nil
+	}
+	var best *model.Cloud
+	bestBits := -1
+	for _, ranges := range *providers {
+		if cloud, prefix, ok := ranges.trie.Lookup(addr); ok && prefix.Bits() > bestBits {
+			best, bestBits = cloud, prefix.Bits()
+		}
+	}
+	return best
+}
+
+// Reload 重新加载全部来源, 加载失败的厂商保留上一次的数据
+func (r *Ranges) Reload(ctx context.Context) {
+	next := map[string]*providerRanges{}
+	previous := r.current.Load()
+
+	for provider, sources := range r.sourcesByProvider() {
+		ranges, err := loadProvider(ctx, provider, sources)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("Failed to load %s ranges: %v", provider, err))
+			if previous != nil && (*previous)[provider] != nil {
+				next[provider] = (*previous)[provider]
+			}
+			continue
+		}
+		logger.SysLog(fmt.Sprintf("Loaded %s ranges with %d prefixes", provider, ranges.count))
+		next[provider] = ranges
+	}
+
+	r.current.Store(&next)
+}
+
+func (r *Ranges) sourcesByProvider() map[string][]string {
+	result := map[string][]string{}
+	for _, item := range r.sources {
+		provider, src, ok := strings.Cut(item, "=")
+		if !ok {
+			logger.SysError(fmt.Sprintf("Invalid cloud range source %q, expected provider=source", item))
+			continue
+		}
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		result[provider] = append(result[provider], strings.TrimSpace(src))
+	}
+	return result
+}
+
+func loadProvider(ctx context.Context, provider string, sources []string) (*providerRanges, error) {
+	parse, ok := parsers[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider %q", provider)
+	}
+
+	best := map[netip.Prefix]rangeEntry{}
+	for _, src := range sources {
+		data, err := common.ReadSource(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		entries, err := parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		for _, entry := range entries {
+			if existing, ok := best[entry.prefix]; !ok || entry.priority > existing.priority {
+				best[entry.prefix] = entry
+			}
+		}
+	}
+
+	trie := &iptrie.Trie[*model.Cloud]{}
+	for prefix, entry := range best {
+		trie.Insert(prefix, newCloud(provider, entry))
+	}
+	return &providerRanges{trie: trie, count: trie.Len()}, nil
+}
+
+func newCloud(provider string, entry rangeEntry) *model.Cloud {
+	id := provider
+	for _, part := range []string{entry.service, entry.region} {
+		if part != "" {
+			id += "/" + part
+		}
+	}
+	return &model.Cloud{
+		ID:       id,
+		Provider: provider,
+		Service:  entry.service,
+		Region:   entry.region,
+		Prefix:   entry.prefix.String(),
+	}
+}
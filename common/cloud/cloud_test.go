@@ -0,0 +1,170 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"testing"
+
+	"go-geoip/model"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		provider, file string
+		want           []string // prefix service region priority
+	}{
+		{"aws", "aws.json", []string{
+			"3.5.140.0/22 amazon ap-northeast-2 0",
+			"3.5.140.0/22 s3 ap-northeast-2 1",
+			"3.0.0.0/15 amazon us-east-1 0",
+			"3.0.5.0/24 ec2 us-east-1 1",
+			"2600:1f14::/35 ec2 us-west-2 1",
+		}},
+		{"gcp", "gcp.json", []string{
+			"34.80.0.0/15 google-cloud asia-east1 1",
+			"2600:1900:4010::/44 google-cloud europe-west1 1",
+		}},
+		{"azure", "azure.json", []string{
+			"20.0.0.0/11 azurecloud  0",
+			"20.8.0.0/16 azurecloud westeurope 0",
+			"20.8.128.0/17 azurestorage  1",
+			"20.8.128.0/17 azurestorage westeurope 2",
+			"2603:1020:200::/46 azurestorage westeurope 2",
+		}},
+		{"cloudflare", "cloudflare-ips-v4.txt", []string{
+			"173.245.48.0/20 cdn  1",
+			"103.21.244.0/22 cdn  1",
+		}},
+		{"cloudflare", "cloudflare.json", []string{
+			"173.245.48.0/20 cdn  1",
+			"2400:cb00::/32 cdn  1",
+		}},
+		{"oracle", "oracle.json", []string{
+			"129.146.0.0/21 oci us-phoenix-1 1",
+			"134.70.8.0/21 osn us-phoenix-1 1",
+			"130.61.0.0/16  eu-frankfurt-1 1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := parsers[tt.provider](data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, fmt.Sprintf("%s %s %s %d", e.prefix, e.service, e.region, e.priority))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("entries:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+
+	for provider, parse := range parsers {
+		if provider == "cloudflare" {
+			continue // 非 JSON 内容按文本格式解析
+		}
+		if _, err := parse([]byte("<html>")); err == nil {
+			t.Errorf("%s: invalid JSON accepted", provider)
+		}
+	}
+}
+
+func newTestRanges(t *testing.T, sources ...string) *Ranges {
+	t.Helper()
+	r := NewRanges(sources)
+	r.Reload(context.Background())
+	return r
+}
+
+// 同一前缀保留优先级最高的记录, 不同前缀按最长前缀匹配, 不同厂商之间同样按最长前缀匹配
+func TestLookupPriority(t *testing.T) {
+	r := newTestRanges(t,
+		"aws=testdata/aws.json",
+		"azure=testdata/azure.json",
+		"cloudflare=testdata/cloudflare-ips-v4.txt",
+		"cloudflare=testdata/cloudflare.json",
+		"oracle=testdata/oracle.json",
+	)
+	tests := []struct {
+		addr string
+		want string // Cloud.ID, 空字符串表示未命中
+	}{
+		{"3.5.140.1", "aws/s3/ap-northeast-2"},          // 与 AMAZON 汇总相同的前缀, 具体服务优先
+		{"3.0.5.9", "aws/ec2/us-east-1"},                // 更长的前缀优先
+		{"3.1.0.1", "aws/amazon/us-east-1"},             // 只有汇总前缀
+		{"20.8.200.1", "azure/azurestorage/westeurope"}, // 带区域的服务标签优先级最高
+		{"20.8.1.1", "azure/azurecloud/westeurope"},
+		{"20.1.0.1", "azure/azurecloud"},
+		{"173.245.48.1", "cloudflare/cdn"}, // 两个来源中重复的前缀
+		{"2400:cb00::1", "cloudflare/cdn"},
+		{"130.61.1.1", "oracle/eu-frankfurt-1"},
+		{"8.8.8.8", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if cloud := r.Lookup(netip.MustParseAddr(tt.addr)); cloud != nil {
+			got = cloud.ID
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestReloadKeepsPreviousOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	src := dir + "/aws.json"
+	data, _ := os.ReadFile("testdata/aws.json")
+	if err := os.WriteFile(src, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRanges(t, "aws="+src, "unknown=testdata/aws.json")
+	if r.Lookup(netip.MustParseAddr("3.0.5.9")) == nil {
+		t.Fatal("aws ranges not loaded")
+	}
+
+	if err := os.WriteFile(src, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r.Reload(context.Background())
+	if r.Lookup(netip.MustParseAddr("3.0.5.9")) == nil {
+		t.Error("failed reload dropped the previous aws ranges")
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	r := newTestRanges(t, "aws=testdata/aws.json")
+	ctx := context.Background()
+
+	// 没有其他安全数据时新建 Security
+	info := &model.IPInfoResponse{}
+	r.Enrich(ctx, netip.MustParseAddr("3.0.5.9"), info)
+	if info.Cloud == nil || info.Cloud.Service != "ec2" || info.Cloud.Prefix != "3.0.5.0/24" {
+		t.Errorf("Cloud = %+v", info.Cloud)
+	}
+	if info.Security == nil || !info.Security.IsHosting || !slices.Equal(info.Security.Sources, []string{"cloud_ranges"}) {
+		t.Errorf("Security = %+v, want hosting from cloud_ranges", info.Security)
+	}
+
+	// 已有的安全数据保留, 追加来源
+	info = &model.IPInfoResponse{Security: &model.Security{IsVPN: true, Sources: []string{"vpn"}}}
+	r.Enrich(ctx, netip.MustParseAddr("3.0.5.9"), info)
+	if !info.Security.IsVPN || !info.Security.IsHosting || !slices.Equal(info.Security.Sources, []string{"vpn", "cloud_ranges"}) {
+		t.Errorf("Security = %+v", info.Security)
+	}
+
+	info = &model.IPInfoResponse{}
+	r.Enrich(ctx, netip.MustParseAddr("8.8.8.8"), info)
+	if info.Cloud != nil || info.Security != nil {
+		t.Errorf("miss set Cloud %+v, Security %+v", info.Cloud, info.Security)
+	}
+}
@@ -0,0 +1,191 @@
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"strings"
+)
+
+// rangeEntry 解析出的一条前缀记录, priority 越大越具体, 同一前缀重复出现时保留 priority 最大的一条
+type rangeEntry struct {
+	prefix   netip.Prefix
+	service  string
+	region   string
+	priority int
+}
+
+type parser func(data []byte) ([]rangeEntry, error)
+
+var parsers = map[string]parser{
+	"aws":        parseAWS,
+	"gcp":        parseGCP,
+	"azure":      parseAzure,
+	"cloudflare": parseCloudflare,
+	"oracle":     parseOracle,
+}
+
+// parseAWS https://ip-ranges.amazonaws.com/ip-ranges.json, 服务 AMAZON 是所有前缀的汇总, 优先级最低
+func parseAWS(data []byte) ([]rangeEntry, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+			Service  string `json:"service"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var entries []rangeEntry
+	add := func(cidr, service, region string) {
+		priority := 1
+		if service == "AMAZON" {
+			priority = 0
+		}
+		entries = appendEntry(entries, cidr, service, region, priority)
+	}
+	for _, p := range doc.Prefixes {
+		add(p.IPPrefix, p.Service, p.Region)
+	}
+	for _, p := range doc.IPv6Prefixes {
+		add(p.IPv6Prefix, p.Service, p.Region)
+	}
+	return entries, nil
+}
+
+// parseGCP https://www.gstatic.com/ipranges/cloud.json
+func parseGCP(data []byte) ([]rangeEntry, error) {
+	var doc struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Service    string `json:"service"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var entries []rangeEntry
+	for _, p := range doc.Prefixes {
+		cidr := p.IPv4Prefix
+		if cidr == "" {
+			cidr = p.IPv6Prefix
+		}
+		entries = appendEntry(entries, cidr, p.Service, p.Scope, 1)
+	}
+	return entries, nil
+}
+
+// parseAzure ServiceTags_Public_*.json, 带 systemService 的服务标签比 AzureCloud 等汇总标签更具体
+func parseAzure(data []byte) ([]rangeEntry, error) {
+	var doc struct {
+		Values []struct {
+			Name       string `json:"name"`
+			Properties struct {
+				Region          string   `json:"region"`
+				SystemService   string   `json:"systemService"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var entries []rangeEntry
+	for _, v := range doc.Values {
+		service, priority := v.Properties.SystemService, 0
+		if service != "" {
+			priority = 1
+			if v.Properties.Region != "" {
+				priority = 2
+			}
+		} else {
+			service, _, _ = strings.Cut(v.Name, ".")
+		}
+		for _, cidr := range v.Properties.AddressPrefixes {
+			entries = appendEntry(entries, cidr, service, v.Properties.Region, priority)
+		}
+	}
+	return entries, nil
+}
+
+// parseCloudflare 支持 https://www.cloudflare.com/ips-v4 文本格式及 https://api.cloudflare.com/client/v4/ips JSON 格式
+func parseCloudflare(data []byte) ([]rangeEntry, error) {
+	trimmed := bytes.TrimSpace(data)
+	var cidrs []string
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc struct {
+			Result struct {
+				IPv4CIDRs []string `json:"ipv4_cidrs"`
+				IPv6CIDRs []string `json:"ipv6_cidrs"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		cidrs = append(doc.Result.IPv4CIDRs, doc.Result.IPv6CIDRs...)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			cidrs = append(cidrs, strings.TrimSpace(scanner.Text()))
+		}
+	}
+	var entries []rangeEntry
+	for _, cidr := range cidrs {
+		entries = appendEntry(entries, cidr, "cdn", "", 1)
+	}
+	return entries, nil
+}
+
+// parseOracle https://docs.oracle.com/en-us/iaas/tools/public_ip_ranges.json
+func parseOracle(data []byte) ([]rangeEntry, error) {
+	var doc struct {
+		Regions []struct {
+			Region string `json:"region"`
+			CIDRs  []struct {
+				CIDR string   `json:"cidr"`
+				Tags []string `json:"tags"`
+			} `json:"cidrs"`
+		} `json:"regions"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var entries []rangeEntry
+	for _, r := range doc.Regions {
+		for _, c := range r.CIDRs {
+			service := ""
+			if len(c.Tags) > 0 {
+				service = c.Tags[0]
+			}
+			entries = appendEntry(entries, c.CIDR, service, r.Region, 1)
+		}
+	}
+	return entries, nil
+}
+
+func appendEntry(entries []rangeEntry, cidr, service, region string, priority int) []rangeEntry {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return entries
+	}
+	return append(entries, rangeEntry{
+		prefix:   prefix.Masked(),
+		service:  normalizeName(service),
+		region:   normalizeName(region),
+		priority: priority,
+	})
+}
+
+// normalizeName 转小写并以 - 连接空格, 如 "Google Cloud" -> "google-cloud"
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), "-")
+}
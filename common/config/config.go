@@ -15,14 +15,128 @@ var SessionSecret = uuid.New().String()
 
 var RateLimitKeyExpirationDuration = 20 * time.Minute
 var SwaggerEnable = os.Getenv("SWAGGER_ENABLE")
+
+// MetricsEnable 为 false 时不提供 /metrics; 默认需要 admin 密钥, MetricsPublic 为 true 时无需密钥
+var (
+	MetricsEnable = env.Bool("METRICS_ENABLE", true)
+	MetricsPublic = env.Bool("METRICS_PUBLIC", false)
+)
+
+// 链路追踪: 配置 OTLP 地址后通过 OTLP/HTTP 导出 span, 未配置时只透传 W3C traceparent;
+// 采样率、请求头等其余 OTEL_* 变量由 OpenTelemetry SDK 按规范读取
+var (
+	OTLPEndpoint    = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	OTelServiceName = env.String("OTEL_SERVICE_NAME", "go-geoip")
+)
+
 var ApiSecret = os.Getenv("API_SECRET")
 var ApiSecrets = strings.Split(os.Getenv("API_SECRET"), ",")
 
+// APIKeysFile 密钥存储文件, 通过 /admin/keys 管理; AdminSecret 用于调用管理接口创建第一个密钥
+var (
+	APIKeysFile = env.String("API_KEYS_FILE", "api-keys.json")
+	AdminSecret = os.Getenv("ADMIN_SECRET")
+)
+
+// JWT 鉴权: JWT_SECRET 用于 HS256, JWT_PUBLIC_KEYS (PEM 文件, 逗号分隔) 与 JWT_JWKS_FILE 用于 RS256/EdDSA;
+// 声明中的 scope (空格分隔或数组) 映射为权限, tier 按 RATE_LIMIT_TIERS (如 free:60,pro:600) 映射为每个限流窗口的请求数
+var (
+	JWTSecret      = os.Getenv("JWT_SECRET")
+	JWTPublicKeys  = env.Strings("JWT_PUBLIC_KEYS", nil)
+	JWTJWKSFile    = os.Getenv("JWT_JWKS_FILE")
+	JWTAudience    = os.Getenv("JWT_AUDIENCE")
+	JWTIssuer      = os.Getenv("JWT_ISSUER")
+	JWTScopeClaim  = env.String("JWT_SCOPE_CLAIM", "scope")
+	JWTTierClaim   = env.String("JWT_TIER_CLAIM", "tier")
+	RateLimitTiers = env.Strings("RATE_LIMIT_TIERS", nil)
+)
+
+// HMAC 签名请求: HMAC_KEYS 为 id:secret 列表 (逗号分隔), 时间戳与服务器时间相差超过 HMAC_REPLAY_WINDOW 秒的请求被拒绝
+var (
+	HMACKeys         = env.Strings("HMAC_KEYS", nil)
+	HMACReplayWindow = env.Int("HMAC_REPLAY_WINDOW", 300)
+)
+
+// TLS: 配置证书与私钥后所有监听地址改为 HTTPS, 文件更新后自动重新加载;
+// 配置 TLS_CLIENT_CA_FILE 后校验客户端证书 (mTLS), 证书的 SAN 或 CN 按 MTLS_IDENTITIES (如 spiffe://mesh/web=lookup|batch) 映射为权限
+var (
+	TLSCertFile           = os.Getenv("TLS_CERT_FILE")
+	TLSKeyFile            = os.Getenv("TLS_KEY_FILE")
+	TLSClientCAFile       = os.Getenv("TLS_CLIENT_CA_FILE")
+	TLSClientCertRequired = env.Bool("TLS_CLIENT_CERT_REQUIRED", true)
+	MTLSIdentities        = env.Strings("MTLS_IDENTITIES", nil)
+)
+
+// UsageFile 按密钥、按月累计用量的账本文件, 由 /usage 查询
+var UsageFile = env.String("USAGE_FILE", "usage.json")
+
+// PublicRoutes 额外开放为无需密钥的路由 (按注册路径, 如 /ip/:ip,/info), 管理接口不受影响
+var PublicRoutes = env.Strings("PUBLIC_ROUTES", nil)
+
 var CityDBRemoteUrl = os.Getenv("CITY_DB_REMOTE_URL")
 var AsnDBRemoteUrl = os.Getenv("ASN_DB_REMOTE_URL")
 var CnDBRemoteUrl = os.Getenv("CN_DB_REMOTE_URL")
 
+// 限流: 每个 RATE_LIMIT_WINDOW 秒允许 REQUEST_RATE_LIMIT 次请求, 突发上限为 RATE_LIMIT_BURST (0 为等于 REQUEST_RATE_LIMIT);
+// ROUTE_RATE_LIMITS (如 /ip/batch=10) 为单个路由额外设置每个窗口的请求数, RATE_LIMIT_ALLOWLIST 中的 CIDR 不限流
+var (
+	RequestRateLimitNum = env.Int("REQUEST_RATE_LIMIT", 120)
+	RateLimitWindow     = env.Int("RATE_LIMIT_WINDOW", 60)
+	RateLimitBurst      = env.Int("RATE_LIMIT_BURST", 0)
+	RouteRateLimits     = env.Strings("ROUTE_RATE_LIMITS", nil)
+	RateLimitAllowlist  = env.Strings("RATE_LIMIT_ALLOWLIST", nil)
+)
+
+// RateLimitRedisURL 配置后多个副本通过 Redis 共享限流计数 (如 redis://127.0.0.1:6379/0), Redis 不可用时退回本地限流
+var RateLimitRedisURL = os.Getenv("RATE_LIMIT_REDIS_URL")
+
+// ErrorFormat 为 problem 时错误响应统一使用 RFC 7807 application/problem+json
+var ErrorFormat = strings.ToLower(os.Getenv("ERROR_FORMAT"))
+
+// StrictLookup 为 true 时默认启用严格模式, 所有数据库均未命中返回 404 (可被 ?strict= 覆盖)
+var StrictLookup = env.Bool("STRICT_LOOKUP", false)
+
+// StrictIPParsing 为 true 时只接受标准写法的 IP; 默认还接受 [IPv6]、区域标识 (%eth0) 及 IPv4 各段的前导零
+var StrictIPParsing = env.Bool("STRICT_IP_PARSING", false)
+
+// 反向解析 (rdns=1) 配置, RDNS_RESOLVER 为空时使用系统解析器
+var (
+	RDNSResolver  = os.Getenv("RDNS_RESOLVER")
+	RDNSTimeout   = env.Int("RDNS_TIMEOUT_MS", 1000)
+	RDNSCacheTTL  = env.Int("RDNS_CACHE_TTL", 3600)
+	RDNSCacheSize = env.Int("RDNS_CACHE_SIZE", 10000)
+)
+
+// 安全标记数据源: 可选的 Anonymous-IP 数据库及公开 IP 列表 (逗号分隔, 支持 URL 或本地路径)
+var (
+	AnonymousDBRemoteUrl = os.Getenv("ANONYMOUS_DB_REMOTE_URL")
+	TorExitListSources   = env.Strings("TOR_EXIT_LIST_SOURCES", nil)
+	VPNListSources       = env.Strings("VPN_LIST_SOURCES", nil)
+	ProxyListSources     = env.Strings("PROXY_LIST_SOURCES", nil)
+	HostingListSources   = env.Strings("HOSTING_LIST_SOURCES", nil)
+)
+
+// CloudRangeSources 云厂商公开 IP 段, 格式 provider=URL或本地路径, 逗号分隔; provider 支持 aws/gcp/azure/cloudflare/oracle
+var CloudRangeSources = env.Strings("CLOUD_RANGE_SOURCES", nil)
+
+// 网段查询 (/network) 分页: 默认每页条数、单页上限与偏移量上限 (每页都从头遍历网段, 偏移量越大越慢)
+var (
+	NetworkDefaultLimit = env.Int("NETWORK_DEFAULT_LIMIT", 100)
+	NetworkMaxLimit     = env.Int("NETWORK_MAX_LIMIT", 1000)
+	NetworkMaxOffset    = env.Int("NETWORK_MAX_OFFSET", 100000)
+)
+
+// 批量查询: 单次请求的 IP 数量上限, 以及每个请求并发查询的 worker 数
+var (
+	BatchMaxIPs  = env.Int("BATCH_MAX_IPS", 100)
+	BatchWorkers = env.Int("BATCH_WORKERS", 16)
+)
+
+// 双栈自助查询: LISTEN_IPV4/LISTEN_IPV6 为额外的单地址族监听地址 (如 0.0.0.0:7098、[::]:7097),
+// IPV4_BASE_URL/IPV6_BASE_URL 为仅解析到 A/AAAA 记录的主机名, 由 /dualstack 返回给浏览器
 var (
-	RequestRateLimitNum            = env.Int("REQUEST_RATE_LIMIT", 120)
-	RequestRateLimitDuration int64 = 1 * 60
+	ListenIPv4  = os.Getenv("LISTEN_IPV4")
+	ListenIPv6  = os.Getenv("LISTEN_IPV6")
+	IPv4BaseURL = os.Getenv("IPV4_BASE_URL")
+	IPv6BaseURL = os.Getenv("IPV6_BASE_URL")
 )
@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 func Bool(env string, defaultValue bool) bool {
@@ -40,3 +41,17 @@ func String(env string, defaultValue string) string {
 	}
 	return os.Getenv(env)
 }
+
+// Strings 按逗号分隔读取列表, 忽略空项
+func Strings(env string, defaultValue []string) []string {
+	if env == "" || os.Getenv(env) == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, v := range strings.Split(os.Getenv(env), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIError 是对外暴露的错误,包含 HTTP 状态码、数字错误码与机器可读的 key
+type APIError struct {
+	Status  int
+	Code    int
+	Key     string
+	Message string
+	Detail  string
+}
+
+// 错误码目录, 数字码的前三位与 HTTP 状态码一致
+var (
+	ErrInvalidIP       = &APIError{Status: http.StatusBadRequest, Code: 40001, Key: "invalid_ip", Message: "invalid IP address"}
+	ErrReservedAddress = &APIError{Status: http.StatusBadRequest, Code: 40002, Key: "reserved_address", Message: "reserved IP address"}
+	ErrInvalidNetwork  = &APIError{Status: http.StatusBadRequest, Code: 40003, Key: "invalid_network", Message: "invalid network"}
+	ErrInvalidParam    = &APIError{Status: http.StatusBadRequest, Code: 40004, Key: "invalid_parameter", Message: "invalid parameter"}
+	ErrUnauthorized    = &APIError{Status: http.StatusUnauthorized, Code: 40101, Key: "unauthorized", Message: "auth fail"}
+	ErrForbidden       = &APIError{Status: http.StatusForbidden, Code: 40301, Key: "forbidden", Message: "insufficient scope"}
+	ErrNotFound        = &APIError{Status: http.StatusNotFound, Code: 40401, Key: "not_found", Message: "not found"}
+	ErrRateLimited     = &APIError{Status: http.StatusTooManyRequests, Code: 42901, Key: "rate_limited", Message: "请求过于频繁,请稍后再试"}
+	ErrQuotaExceeded   = &APIError{Status: http.StatusTooManyRequests, Code: 42902, Key: "quota_exceeded", Message: "daily quota exceeded"}
+	ErrInternal        = &APIError{Status: http.StatusInternalServerError, Code: 50001, Key: "internal_error", Message: "internal error"}
+	ErrDBUnavailable   = &APIError{Status: http.StatusServiceUnavailable, Code: 50301, Key: "db_unavailable", Message: "database unavailable"}
+)
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Message + ": " + e.Detail
+	}
+	return e.Message
+}
+
+// Is 按 key 比较, 使 errors.Is(err, ErrInvalidIP) 对带 Detail 的副本同样成立
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Key == e.Key
+}
+
+// WithDetail 返回附带详细信息的副本, 不修改目录中的原始错误
+func (e *APIError) WithDetail(format string, a ...any) *APIError {
+	c := *e
+	c.Detail = fmt.Sprintf(format, a...)
+	return &c
+}
+
+// AsAPIError 将任意错误转换为 APIError, 未登记的错误视为 internal_error
+func AsAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return ErrInternal.WithDetail("%s", err.Error())
+}
@@ -1,8 +1,14 @@
 package common
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 func fileExistsInDir(dir, filename string) (bool, error) {
@@ -21,3 +27,26 @@ func fileExistsInDir(dir, filename string) (bool, error) {
 	// 判断是否是文件
 	return !info.IsDir(), nil
 }
+
+// sourceClient 下载数据源的 HTTP 客户端, 超时覆盖连接到读完响应体, 避免无响应的源阻塞数据库更新
+var sourceClient = &http.Client{Timeout: time.Minute}
+
+// ReadSource 读取数据源, http(s) 开头的按 URL 下载, 否则按本地路径读取
+func ReadSource(ctx context.Context, src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sourceClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
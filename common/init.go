@@ -8,11 +8,12 @@ import (
 	"path/filepath"
 )
 
+// 命令行参数, 由 ParseFlags 注册并解析
 var (
-	Port         = flag.Int("port", 7099, "the listening port")
-	PrintVersion = flag.Bool("version", false, "print version and exit")
-	PrintHelp    = flag.Bool("help", false, "print help and exit")
-	LogDir       = flag.String("log-dir", "", "specify the log directory")
+	Port         *int
+	PrintVersion *bool
+	PrintHelp    *bool
+	LogDir       *string
 )
 
 // UploadPath Maybe override by ENV_VAR
@@ -23,9 +24,25 @@ func printHelp() {
 	fmt.Println("Copyright (C) 2024 Dean. All rights reserved.")
 	//fmt.Println("GitHub: https://github.com/deanxv/go-geoip ")
 	fmt.Println("Usage: go-geoip [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("       go-geoip lookup [--rdns] <ip>... | -   look up IPs (- reads IPs from stdin), one JSON line each")
+	fmt.Println("       go-geoip update                         download and verify databases, then exit")
+	fmt.Println("       go-geoip info                           print database metadata")
+	fmt.Println("       go-geoip export [--format csv|jsonl] [--output <file>]")
+	fmt.Println("       go-geoip enrich [--format nginx-combined|apache-combined|apache-common] [--regex <re>] [--output jsonl|append] [file...]")
 }
 
 func init() {
+	if os.Getenv("UPLOAD_PATH") != "" {
+		UploadPath = os.Getenv("UPLOAD_PATH")
+	}
+}
+
+// ParseFlags 由 main 在启动时调用; 不放在 init 中, 以免导入 common 的程序 (如使用 client 包的程序) 的命令行被接管
+func ParseFlags() {
+	Port = flag.Int("port", 7099, "the listening port")
+	PrintVersion = flag.Bool("version", false, "print version and exit")
+	PrintHelp = flag.Bool("help", false, "print help and exit")
+	LogDir = flag.String("log-dir", "", "specify the log directory")
 	flag.Parse()
 
 	if *PrintVersion {
@@ -38,9 +55,6 @@ func init() {
 		os.Exit(0)
 	}
 
-	if os.Getenv("UPLOAD_PATH") != "" {
-		UploadPath = os.Getenv("UPLOAD_PATH")
-	}
 	if *LogDir != "" {
 		var err error
 		*LogDir, err = filepath.Abs(*LogDir)
@@ -0,0 +1,74 @@
+package iptrie
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// Collapse 合并相邻与重叠的前缀, 返回覆盖相同地址的最少 CIDR 列表, 结果按地址排序
+func Collapse(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p = normalize(p); p.IsValid() {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	var stack []netip.Prefix
+	for _, p := range sorted {
+		// 已被栈顶前缀包含
+		if n := len(stack); n > 0 && stack[n-1].Bits() <= p.Bits() && stack[n-1].Contains(p.Addr()) {
+			continue
+		}
+		stack = append(stack, p)
+		// 栈顶两个前缀互为兄弟时合并为父前缀, 合并后可能继续与更早的前缀合并
+		for len(stack) >= 2 {
+			a, b := stack[len(stack)-2], stack[len(stack)-1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().Is4() != b.Addr().Is4() {
+				break
+			}
+			parent, _ := a.Addr().Prefix(a.Bits() - 1)
+			if parent.Addr() != a.Addr() || !parent.Contains(b.Addr()) {
+				break
+			}
+			stack = append(stack[:len(stack)-2], parent)
+		}
+	}
+	return stack
+}
+
+// LastAddr 返回前缀中的最后一个地址
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	prefix = prefix.Masked()
+	bytes := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// RangeToPrefixes 将 [start, end] 地址区间拆分为最少的 CIDR 列表
+func RangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for start.IsValid() && start.Compare(end) <= 0 {
+		bits := start.BitLen()
+		for bits > 0 {
+			parent, _ := start.Prefix(bits - 1)
+			if parent.Addr() != start || LastAddr(parent).Compare(end) > 0 {
+				break
+			}
+			bits--
+		}
+		prefix := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, prefix)
+		start = LastAddr(prefix).Next()
+	}
+	return prefixes
+}
@@ -0,0 +1,75 @@
+package iptrie
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func prefixes(s string) []netip.Prefix {
+	var result []netip.Prefix
+	for _, p := range strings.Fields(s) {
+		result = append(result, netip.MustParsePrefix(p))
+	}
+	return result
+}
+
+func TestCollapse(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"siblings", "10.0.0.0/25 10.0.0.128/25", "10.0.0.0/24"},
+		{"unsorted with cascade", "10.0.0.128/25 10.0.0.64/26 10.0.0.0/26", "10.0.0.0/24"},
+		{"contained and duplicate", "10.0.0.0/16 10.0.3.0/24 10.0.0.0/16", "10.0.0.0/16"},
+		{"adjacent but not siblings", "10.0.0.128/25 10.0.1.0/25", "10.0.0.128/25 10.0.1.0/25"},
+		{"host bits are masked", "10.0.0.1/25 10.0.0.200/25", "10.0.0.0/24"},
+		{"whole address space", "0.0.0.0/1 128.0.0.0/1", "0.0.0.0/0"},
+		{"families are not merged", "0.0.0.0/1 ::/1 128.0.0.0/1 8000::/1", "0.0.0.0/0 ::/0"},
+		{"ipv4-mapped ipv6 is unmapped", "::ffff:10.0.0.0/120 10.0.1.0/24", "10.0.0.0/23"},
+		{"ipv6", "2001:db8::/33 2001:db8:8000::/33 2001:db8:1::/48", "2001:db8::/32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Collapse(prefixes(tt.in)); !slices.Equal(got, prefixes(tt.want)) {
+				t.Errorf("Collapse(%s) = %v, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end, want string
+	}{
+		{"10.0.0.0", "10.0.0.255", "10.0.0.0/24"},
+		{"10.0.0.1", "10.0.0.1", "10.0.0.1/32"},
+		{"10.0.0.1", "10.0.0.6", "10.0.0.1/32 10.0.0.2/31 10.0.0.4/31 10.0.0.6/32"},
+		{"10.0.0.0", "10.0.2.127", "10.0.0.0/23 10.0.2.0/25"},
+		{"0.0.0.0", "255.255.255.255", "0.0.0.0/0"},
+		{"255.255.255.254", "255.255.255.255", "255.255.255.254/31"},
+		{"2001:db8::", "2001:db8::1:ffff", "2001:db8::/111"},
+		{"10.0.0.5", "10.0.0.4", ""},
+	}
+	for _, tt := range tests {
+		got := RangeToPrefixes(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end))
+		if !slices.Equal(got, prefixes(tt.want)) {
+			t.Errorf("RangeToPrefixes(%s, %s) = %v, want %s", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestLastAddr(t *testing.T) {
+	tests := []struct{ prefix, want string }{
+		{"10.0.0.0/24", "10.0.0.255"},
+		{"10.0.0.77/30", "10.0.0.79"},
+		{"10.0.0.1/32", "10.0.0.1"},
+		{"2001:db8::/64", "2001:db8::ffff:ffff:ffff:ffff"},
+	}
+	for _, tt := range tests {
+		if got := LastAddr(netip.MustParsePrefix(tt.prefix)); got != netip.MustParseAddr(tt.want) {
+			t.Errorf("LastAddr(%s) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
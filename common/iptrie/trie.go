@@ -0,0 +1,95 @@
+package iptrie
+
+import "net/netip"
+
+// Trie 是按位展开的二叉前缀树, 支持最长前缀匹配; IPv4 与 IPv6 分开存储, IPv4-mapped IPv6 按 IPv4 处理
+type Trie[V any] struct {
+	v4, v6 *node[V]
+	size   int
+}
+
+type node[V any] struct {
+	children [2]*node[V]
+	value    V
+	set      bool
+}
+
+// Insert 插入前缀, 已存在的前缀会被覆盖
+func (t *Trie[V]) Insert(prefix netip.Prefix, value V) {
+	prefix = normalize(prefix)
+	if !prefix.IsValid() {
+		return
+	}
+	n := t.root(prefix.Addr(), true)
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		b := bit(bytes, i)
+		if n.children[b] == nil {
+			n.children[b] = &node[V]{}
+		}
+		n = n.children[b]
+	}
+	if !n.set {
+		t.size++
+	}
+	n.value = value
+	n.set = true
+}
+
+// Lookup 返回包含 addr 的最长前缀及其值
+func (t *Trie[V]) Lookup(addr netip.Addr) (value V, prefix netip.Prefix, ok bool) {
+	addr = addr.Unmap().WithZone("")
+	n := t.root(addr, false)
+	bytes := addr.AsSlice()
+	for depth := 0; n != nil; depth++ {
+		if n.set {
+			value, ok = n.value, true
+			prefix, _ = addr.Prefix(depth)
+		}
+		if depth == len(bytes)*8 {
+			break
+		}
+		n = n.children[bit(bytes, depth)]
+	}
+	return value, prefix, ok
+}
+
+// Contains 判断 addr 是否落在任一前缀内
+func (t *Trie[V]) Contains(addr netip.Addr) bool {
+	_, _, ok := t.Lookup(addr)
+	return ok
+}
+
+// Len 返回前缀数量
+func (t *Trie[V]) Len() int {
+	return t.size
+}
+
+func (t *Trie[V]) root(addr netip.Addr, create bool) *node[V] {
+	if addr.Is4() {
+		if t.v4 == nil && create {
+			t.v4 = &node[V]{}
+		}
+		return t.v4
+	}
+	if t.v6 == nil && create {
+		t.v6 = &node[V]{}
+	}
+	return t.v6
+}
+
+func normalize(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	if addr.Is4In6() {
+		bits := prefix.Bits() - 96
+		if bits < 0 {
+			return netip.Prefix{}
+		}
+		prefix = netip.PrefixFrom(addr.Unmap(), bits)
+	}
+	return prefix.Masked()
+}
+
+func bit(bytes []byte, i int) int {
+	return int(bytes[i/8]>>(7-i%8)) & 1
+}
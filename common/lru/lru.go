@@ -0,0 +1,71 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache 并发安全的定长 LRU 缓存
+type Cache[K comparable, V any] struct {
+	mutex    sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New capacity <= 0 时缓存不保存任何数据
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	return &Cache[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*entry[K, V]).value, true
+	}
+	return value, false
+}
+
+func (c *Cache[K, V]) Add(key K, value V) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry[K, V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// Purge 清空缓存
+func (c *Cache[K, V]) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go-geoip/model"
+)
+
+const namespace = "geoip"
+
+var (
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	Lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lookups_total",
+		Help:      "Database lookups by database and result (found, not_found, error).",
+	}, []string{"database", "result"})
+
+	EngineAcquireWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "engine_acquire_wait_seconds",
+		Help:      "Time spent waiting for the database lock before a lookup; non-zero while databases are being swapped.",
+		Buckets:   []float64{.000001, .00001, .0001, .001, .01, .1, 1},
+	})
+
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter (rate_limited) or the daily quota (quota_exceeded).",
+	}, []string{"route", "reason"})
+
+	DownloadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "database_download_duration_seconds",
+		Help:      "Duration of database downloads by file.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"file"})
+
+	DownloadFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "database_download_failures_total",
+		Help:      "Failed database downloads by file.",
+	}, []string{"file"})
+)
+
+// EngineObserver 将 geoip.Observer 的事件记录到上面的指标
+type EngineObserver struct{}
+
+func (EngineObserver) ObserveLookup(database, result string) {
+	Lookups.WithLabelValues(database, result).Inc()
+}
+
+func (EngineObserver) ObserveAcquire(wait time.Duration) {
+	EngineAcquireWait.Observe(wait.Seconds())
+}
+
+func (EngineObserver) ObserveCache(cache string, hit bool) {
+	v, loaded := observedCaches.LoadOrStore(cache, &cacheCounter{})
+	counter := v.(*cacheCounter)
+	if !loaded {
+		RegisterCacheStats(cache, counter.stats)
+	}
+	if hit {
+		counter.hits.Add(1)
+	} else {
+		counter.misses.Add(1)
+	}
+}
+
+// observedCaches 通过 ObserveCache 上报的缓存, 首次出现时注册
+var observedCaches sync.Map
+
+type cacheCounter struct {
+	hits, misses atomic.Uint64
+}
+
+func (c *cacheCounter) stats() (uint64, uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// RegisterCacheStats 注册自行维护命中计数的缓存 (如 rdns), 抓取时读取并计算命中率
+func RegisterCacheStats(cache string, stats func() (hits, misses uint64)) {
+	prometheus.MustRegister(&cacheStatsCollector{cache: cache, stats: stats})
+}
+
+var (
+	cacheRequestsDesc = prometheus.NewDesc(namespace+"_cache_requests_total",
+		"Cache lookups by cache and result (hit, miss).", []string{"cache", "result"}, nil)
+	cacheHitRatioDesc = prometheus.NewDesc(namespace+"_cache_hit_ratio",
+		"Fraction of cache lookups that were hits since start.", []string{"cache"}, nil)
+)
+
+type cacheStatsCollector struct {
+	cache string
+	stats func() (hits, misses uint64)
+}
+
+// Describe 不声明描述符, 各缓存的 collector 共用同一组指标名, 由 label 区分
+func (c *cacheStatsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *cacheStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	hits, misses := c.stats()
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(hits), c.cache, "hit")
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(misses), c.cache, "miss")
+	if total := hits + misses; total > 0 {
+		ch <- prometheus.MustNewConstMetric(cacheHitRatioDesc, prometheus.GaugeValue, float64(hits)/float64(total), c.cache)
+	}
+}
+
+// RegisterDatabaseInfo 抓取时读取已加载数据库的构建时间, 输出构建时间戳与距今的秒数
+func RegisterDatabaseInfo(info func() []model.DatabaseInfo) {
+	prometheus.MustRegister(&databaseCollector{info: info})
+}
+
+var (
+	buildEpochDesc = prometheus.NewDesc(namespace+"_database_build_timestamp_seconds",
+		"Build epoch of each loaded database.", []string{"database"}, nil)
+	ageDesc = prometheus.NewDesc(namespace+"_database_age_seconds",
+		"Seconds since each loaded database was built.", []string{"database"}, nil)
+)
+
+type databaseCollector struct {
+	info func() []model.DatabaseInfo
+}
+
+func (c *databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- buildEpochDesc
+	ch <- ageDesc
+}
+
+func (c *databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, db := range c.info() {
+		if !db.Loaded {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(buildEpochDesc, prometheus.GaugeValue, float64(db.BuildEpoch), db.Name)
+		ch <- prometheus.MustNewConstMetric(ageDesc, prometheus.GaugeValue, now.Sub(time.Unix(int64(db.BuildEpoch), 0)).Seconds(), db.Name)
+	}
+}
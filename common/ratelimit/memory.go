@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory 进程内的 GCRA 限流器, 令牌已全部恢复的 key 被定期清理
+type Memory struct {
+	mutex sync.Mutex
+	tats  map[string]time.Time
+}
+
+// NewMemory cleanupInterval 为 0 时不清理
+func NewMemory(cleanupInterval time.Duration) *Memory {
+	m := &Memory{tats: map[string]time.Time{}}
+	if cleanupInterval > 0 {
+		go m.cleanup(cleanupInterval)
+	}
+	return m
+}
+
+func (m *Memory) Allow(_ context.Context, key string, limit Limit, cost int) (Result, error) {
+	if err := limit.Validate(); err != nil {
+		return Result{}, err
+	}
+	now := time.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	tat, result := gcra(now, m.tats[key], limit, cost)
+	m.tats[key] = tat
+	return result, nil
+}
+
+func (m *Memory) cleanup(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		now := time.Now()
+		m.mutex.Lock()
+		for key, tat := range m.tats {
+			if !tat.After(now) {
+				delete(m.tats, key)
+			}
+		}
+		m.mutex.Unlock()
+	}
+}
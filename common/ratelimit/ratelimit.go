@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidLimit 速率或窗口无效, 两次请求的间隔为 0 时无法计算剩余次数
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit 每个 Window 允许 Rate 次请求, 最多可以一次性使用 Burst 次; Burst 为 0 时等于 Rate
+type Limit struct {
+	Rate   int
+	Window time.Duration
+	Burst  int
+}
+
+// Validate 要求 Rate 与 Burst 为正数, 且窗口至少为 Rate 纳秒 (interval 不为 0)
+func (l Limit) Validate() error {
+	if l.Rate <= 0 || l.Burst < 0 || l.Window < time.Duration(l.Rate) {
+		return fmt.Errorf("%w: %d per %v", ErrInvalidLimit, l.Rate, l.Window)
+	}
+	return nil
+}
+
+// interval 两次请求之间的平均间隔 (GCRA 中的 emission interval)
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Rate)
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// clampCost 单次请求最多消耗一整桶令牌, 否则超过 Burst 的请求永远无法通过
+func (l Limit) clampCost(cost int) int {
+	return min(max(cost, 1), l.burst())
+}
+
+// Result 一次判定的结果, 用于填充 RateLimit-* 响应头
+type Result struct {
+	Allowed    bool
+	Limit      int // 可一次性使用的令牌数, 即 Burst (未设置时为 Rate)
+	Remaining  int
+	RetryAfter time.Duration // 被拒绝时距离可以重试的时间
+	ResetAfter time.Duration // 距离令牌全部恢复的时间
+}
+
+// Limiter 按 key 计数的限流器, cost 为本次请求消耗的令牌数
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit, cost int) (Result, error)
+}
+
+// gcra 以理论到达时间 (TAT) 实现令牌桶, 每个 key 只需保存一个时间戳; 返回新的 TAT 与判定结果
+func gcra(now, tat time.Time, limit Limit, cost int) (time.Time, Result) {
+	cost = limit.clampCost(cost)
+	interval := limit.interval()
+	tolerance := interval * time.Duration(limit.burst())
+	if tat.Before(now) {
+		tat = now
+	}
+	// RateLimit-Limit 报告桶的容量, 与 Remaining 的取值范围一致
+	result := Result{Limit: limit.burst()}
+
+	newTat := tat.Add(interval * time.Duration(cost))
+	if allowAt := newTat.Add(-tolerance); now.Before(allowAt) {
+		result.RetryAfter = allowAt.Sub(now)
+		result.ResetAfter = tat.Sub(now)
+		result.Remaining = remaining(now, tat, tolerance, interval)
+		return tat, result
+	}
+
+	result.Allowed = true
+	result.ResetAfter = newTat.Sub(now)
+	result.Remaining = remaining(now, newTat, tolerance, interval)
+	return newTat, result
+}
+
+func remaining(now, tat time.Time, tolerance, interval time.Duration) int {
+	n := int((tolerance - tat.Sub(now)) / interval)
+	return max(n, 0)
+}
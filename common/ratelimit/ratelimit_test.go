@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGCRA(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	// 每 10 秒 10 次, 即每秒恢复一个令牌
+	limit := Limit{Rate: 10, Window: 10 * time.Second}
+
+	tests := []struct {
+		name  string
+		tat   time.Time
+		limit Limit
+		cost  int
+
+		want    Result
+		wantTat time.Time
+	}{
+		{
+			name: "new key", tat: time.Time{}, limit: limit, cost: 1,
+			want:    Result{Allowed: true, Limit: 10, Remaining: 9, ResetAfter: time.Second},
+			wantTat: now.Add(time.Second),
+		},
+		{
+			name: "tat in the past counts from now", tat: now.Add(-time.Hour), limit: limit, cost: 1,
+			want:    Result{Allowed: true, Limit: 10, Remaining: 9, ResetAfter: time.Second},
+			wantTat: now.Add(time.Second),
+		},
+		{
+			name: "last token", tat: now.Add(9 * time.Second), limit: limit, cost: 1,
+			want:    Result{Allowed: true, Limit: 10, Remaining: 0, ResetAfter: 10 * time.Second},
+			wantTat: now.Add(10 * time.Second),
+		},
+		{
+			name: "empty bucket", tat: now.Add(10 * time.Second), limit: limit, cost: 1,
+			want:    Result{Allowed: false, Limit: 10, Remaining: 0, RetryAfter: time.Second, ResetAfter: 10 * time.Second},
+			wantTat: now.Add(10 * time.Second),
+		},
+		{
+			name: "retry after covers the whole cost", tat: now.Add(8 * time.Second), limit: limit, cost: 5,
+			want:    Result{Allowed: false, Limit: 10, Remaining: 2, RetryAfter: 3 * time.Second, ResetAfter: 8 * time.Second},
+			wantTat: now.Add(8 * time.Second),
+		},
+		{
+			name: "cost is charged in full", tat: time.Time{}, limit: limit, cost: 4,
+			want:    Result{Allowed: true, Limit: 10, Remaining: 6, ResetAfter: 4 * time.Second},
+			wantTat: now.Add(4 * time.Second),
+		},
+		{
+			name: "cost above burst is clamped to a full bucket", tat: time.Time{}, limit: limit, cost: 50,
+			want:    Result{Allowed: true, Limit: 10, Remaining: 0, ResetAfter: 10 * time.Second},
+			wantTat: now.Add(10 * time.Second),
+		},
+		{
+			name: "burst above rate reports the burst as limit", tat: time.Time{}, limit: Limit{Rate: 10, Window: 10 * time.Second, Burst: 20}, cost: 1,
+			want:    Result{Allowed: true, Limit: 20, Remaining: 19, ResetAfter: time.Second},
+			wantTat: now.Add(time.Second),
+		},
+		{
+			name: "burst below rate", tat: now.Add(2 * time.Second), limit: Limit{Rate: 10, Window: 10 * time.Second, Burst: 2}, cost: 1,
+			want:    Result{Allowed: false, Limit: 2, Remaining: 0, RetryAfter: time.Second, ResetAfter: 2 * time.Second},
+			wantTat: now.Add(2 * time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tat, got := gcra(now, tt.tat, tt.limit, tt.cost)
+			if got != tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+			if !tat.Equal(tt.wantTat) {
+				t.Errorf("tat = now%+v, want now%+v", tat.Sub(now), tt.wantTat.Sub(now))
+			}
+			if got.Remaining > got.Limit {
+				t.Errorf("remaining %d exceeds limit %d", got.Remaining, got.Limit)
+			}
+		})
+	}
+}
+
+func TestClampCost(t *testing.T) {
+	limit := Limit{Rate: 10, Window: time.Minute, Burst: 5}
+	tests := []struct{ cost, want int }{
+		{-3, 1},
+		{0, 1},
+		{1, 1},
+		{5, 5},
+		{6, 5},
+		{1000, 5},
+	}
+	for _, tt := range tests {
+		if got := limit.clampCost(tt.cost); got != tt.want {
+			t.Errorf("clampCost(%d) = %d, want %d", tt.cost, got, tt.want)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(0)
+	ctx := context.Background()
+	limit := Limit{Rate: 3, Window: time.Hour}
+
+	for i := range 3 {
+		if result, _ := m.Allow(ctx, "a", limit, 1); !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i+1, result)
+		}
+	}
+	if result, _ := m.Allow(ctx, "a", limit, 1); result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("4th request: %+v, want denied with Retry-After", result)
+	}
+	if result, _ := m.Allow(ctx, "b", limit, 1); !result.Allowed {
+		t.Errorf("other key: %+v, want allowed", result)
+	}
+}
+
+// 窗口为 0 或短于 Rate 纳秒时 interval 为 0, 判定前返回 ErrInvalidLimit 而不是除以零
+func TestInvalidLimit(t *testing.T) {
+	m := NewMemory(0)
+	for _, limit := range []Limit{
+		{Rate: 10, Window: 0},
+		{Rate: 10, Window: 9},
+		{Rate: 0, Window: time.Minute},
+		{Rate: 10, Window: time.Minute, Burst: -1},
+	} {
+		if _, err := m.Allow(context.Background(), "k", limit, 1); !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("Allow(%+v) error = %v, want ErrInvalidLimit", limit, err)
+		}
+	}
+	if err := (Limit{Rate: 10, Window: 10}).Validate(); err != nil {
+		t.Errorf("one request per nanosecond: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T, server *miniredis.Miniredis) *Redis {
+	t.Helper()
+	r, err := NewRedis("redis://"+server.Addr()+"/0", "rl:", NewMemory(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.client.Close() })
+	return r
+}
+
+// 两个副本通过同一个 Redis 共享计数, 脚本的判定与 gcra 一致
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	a, b := newTestRedis(t, server), newTestRedis(t, server)
+	ctx := context.Background()
+	limit := Limit{Rate: 3, Window: time.Hour}
+
+	for i, r := range []*Redis{a, b, a} {
+		result, err := r.Allow(ctx, "k", limit, 1)
+		if err != nil || !result.Allowed || result.Limit != 3 || result.Remaining != 2-i {
+			t.Fatalf("request %d: %+v, %v", i+1, result, err)
+		}
+	}
+	result, err := b.Allow(ctx, "k", limit, 1)
+	if err != nil || result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("4th request: %+v, %v, want denied with Retry-After", result, err)
+	}
+	if !server.Exists("rl:k") {
+		t.Error("key rl:k not stored in redis")
+	}
+	if ttl := server.TTL("rl:k"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("ttl = %v, want within the window", ttl)
+	}
+
+	// 超过突发上限的 cost 按整个桶计算
+	if result, _ := a.Allow(ctx, "big", limit, 10); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("cost above burst: %+v", result)
+	}
+}
+
+// Redis 不可用时退回本地限流, retryInterval 内不再访问 Redis, 之后恢复共享计数
+func TestRedisFallback(t *testing.T) {
+	server := miniredis.RunT(t)
+	r := newTestRedis(t, server)
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Window: time.Hour}
+
+	if result, err := r.Allow(ctx, "k", limit, 1); err != nil || !result.Allowed {
+		t.Fatalf("redis up: %+v, %v", result, err)
+	}
+
+	server.Close()
+	for i := range 2 {
+		result, err := r.Allow(ctx, "k", limit, 1)
+		if err != nil || !result.Allowed || result.Remaining != 1-i {
+			t.Fatalf("fallback request %d: %+v, %v", i+1, result, err)
+		}
+	}
+	if !r.unavailable() {
+		t.Fatal("redis not marked unavailable")
+	}
+	if result, _ := r.Allow(ctx, "k", limit, 1); result.Allowed {
+		t.Errorf("fallback limiter allowed a 3rd request: %+v", result)
+	}
+
+	// 窗口内 Redis 恢复也继续使用本地限流
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := r.Allow(ctx, "other", limit, 1); !result.Allowed || server.Exists("rl:other") {
+		t.Errorf("redis used within the retry interval: %+v", result)
+	}
+
+	// 窗口结束后重新使用 Redis, 本地计数不再生效
+	r.mutex.Lock()
+	r.downUntil = time.Now().Add(-time.Millisecond)
+	r.mutex.Unlock()
+	result, err := r.Allow(ctx, "k", limit, 1)
+	if err != nil || !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after recovery: %+v, %v, want the shared count", result, err)
+	}
+	if r.unavailable() || !r.downUntil.IsZero() {
+		t.Error("redis still marked unavailable after a successful call")
+	}
+}
@@ -0,0 +1,179 @@
+package rdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-geoip/common/config"
+	"go-geoip/common/lru"
+	"go-geoip/model"
+)
+
+type cacheEntry struct {
+	result    *model.ReverseDNS
+	expiresAt time.Time
+}
+
+// Resolver 反向解析 PTR 记录并做正向确认, 结果按 TTL 缓存, 缓存条数超出上限时淘汰最久未使用的 IP
+type Resolver struct {
+	resolver *net.Resolver
+	timeout  time.Duration
+	ttl      time.Duration
+
+	cache *lru.Cache[string, cacheEntry]
+
+	hits, misses atomic.Uint64
+}
+
+// 未指定时使用的超时、缓存时间与缓存条数, 与 RDNS_TIMEOUT_MS、RDNS_CACHE_TTL、RDNS_CACHE_SIZE 的默认值一致
+const (
+	DefaultTimeout   = time.Second
+	DefaultTTL       = time.Hour
+	DefaultCacheSize = 10000
+)
+
+var (
+	defaultResolver *Resolver
+	once            sync.Once
+)
+
+// Default 返回按 RDNS_* 环境变量配置的单例
+func Default() *Resolver {
+	once.Do(func() {
+		defaultResolver = New(config.RDNSResolver,
+			time.Duration(config.RDNSTimeout)*time.Millisecond,
+			time.Duration(config.RDNSCacheTTL)*time.Second,
+			config.RDNSCacheSize)
+	})
+	return defaultResolver
+}
+
+// New server 为空时使用系统解析器, 否则所有查询发往 server (host:port); ttl 或 cacheSize <= 0 时不缓存
+func New(server string, timeout, ttl time.Duration, cacheSize int) *Resolver {
+	if ttl <= 0 {
+		cacheSize = 0
+	}
+	r := &Resolver{
+		resolver: net.DefaultResolver,
+		timeout:  timeout,
+		ttl:      ttl,
+		cache:    lru.New[string, cacheEntry](cacheSize),
+	}
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return r
+}
+
+func (r *Resolver) Timeout() time.Duration {
+	return r.timeout
+}
+
+// Lookup 最多阻塞 timeout, 超时返回带 error 的结果而不是等待解析完成
+func (r *Resolver) Lookup(ctx context.Context, ip string) *model.ReverseDNS {
+	if result, ok := r.cached(ip); ok {
+		r.hits.Add(1)
+		return result
+	}
+	r.misses.Add(1)
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	done := make(chan *model.ReverseDNS, 1)
+	go func() {
+		done <- r.resolve(ctx, ip)
+	}()
+
+	timeout := &model.ReverseDNS{Hostnames: []model.PTRRecord{}, Error: "timeout"}
+	select {
+	case result := <-done:
+		// 到达截止时间时解析器可能先于 ctx 返回 i/o timeout 等错误, 统一为 timeout
+		if deadline, _ := ctx.Deadline(); result.Error != "" && !time.Now().Before(deadline) {
+			return timeout
+		}
+		return result
+	case <-ctx.Done():
+		return timeout
+	}
+}
+
+// Stats 返回缓存命中与未命中的累计次数
+func (r *Resolver) Stats() (hits, misses uint64) {
+	return r.hits.Load(), r.misses.Load()
+}
+
+// cached 过期的数据留在缓存中, 由下一次解析的结果覆盖或被 LRU 淘汰
+func (r *Resolver) cached(ip string) (*model.ReverseDNS, bool) {
+	entry, ok := r.cache.Get(ip)
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+func (r *Resolver) store(ip string, result *model.ReverseDNS) {
+	r.cache.Add(ip, cacheEntry{result: result, expiresAt: time.Now().Add(r.ttl)})
+}
+
+func (r *Resolver) resolve(ctx context.Context, ip string) *model.ReverseDNS {
+	result := &model.ReverseDNS{Hostnames: []model.PTRRecord{}}
+
+	names, err := r.resolver.LookupAddr(ctx, ip)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			r.store(ip, result)
+			return result
+		}
+		result.Error = err.Error()
+		return result
+	}
+
+	records := make([]model.PTRRecord, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		records[i].Hostname = strings.TrimSuffix(name, ".")
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			records[i].ForwardConfirmed = r.forwardConfirmed(ctx, name, ip)
+		}(i, name)
+	}
+	wg.Wait()
+
+	result.Hostnames = records
+	if ctx.Err() == nil {
+		r.store(ip, result)
+	}
+	return result
+}
+
+// forwardConfirmed 检查 PTR 主机名的 A/AAAA 记录是否指回原 IP (FCrDNS)
+func (r *Resolver) forwardConfirmed(ctx context.Context, name, ip string) bool {
+	target := net.ParseIP(ip)
+	addrs, err := r.resolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if addr.IP.Equal(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package rdns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go-geoip/model"
+)
+
+// 不响应的 DNS 服务器: Lookup 在超时后返回 timeout, 且结果不进入缓存
+func TestLookupTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	r := New(conn.LocalAddr().String(), 50*time.Millisecond, time.Hour, 10)
+	for range 2 {
+		start := time.Now()
+		result := r.Lookup(context.Background(), "192.0.2.1")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Lookup blocked for %v with a 50ms timeout", elapsed)
+		}
+		if result.Error != "timeout" || len(result.Hostnames) != 0 {
+			t.Errorf("result = %+v, want timeout", result)
+		}
+	}
+	if hits, misses := r.Stats(); hits != 0 || misses != 2 {
+		t.Errorf("hits %d, misses %d; timeout was cached", hits, misses)
+	}
+}
+
+func TestCache(t *testing.T) {
+	result := &model.ReverseDNS{Hostnames: []model.PTRRecord{{Hostname: "dns.google", ForwardConfirmed: true}}}
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		cacheSize int
+		store     []string
+		wait      time.Duration
+		want      map[string]bool // ip 是否命中
+	}{
+		{"hit", time.Hour, 10, []string{"8.8.8.8"}, 0, map[string]bool{"8.8.8.8": true, "8.8.4.4": false}},
+		{"expired", 20 * time.Millisecond, 10, []string{"8.8.8.8"}, 50 * time.Millisecond, map[string]bool{"8.8.8.8": false}},
+		{"least recently used evicted", time.Hour, 2, []string{"1.1.1.1", "8.8.8.8", "1.1.1.1", "8.8.4.4"}, 0,
+			map[string]bool{"1.1.1.1": true, "8.8.8.8": false, "8.8.4.4": true}},
+		{"disabled by ttl", 0, 10, []string{"8.8.8.8"}, 0, map[string]bool{"8.8.8.8": false}},
+		{"disabled by size", time.Hour, 0, []string{"8.8.8.8"}, 0, map[string]bool{"8.8.8.8": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New("", time.Second, tt.ttl, tt.cacheSize)
+			for _, ip := range tt.store {
+				if _, ok := r.cached(ip); !ok {
+					r.store(ip, result)
+				}
+			}
+			time.Sleep(tt.wait)
+			for ip, want := range tt.want {
+				if _, ok := r.cached(ip); ok != want {
+					t.Errorf("cached(%s) = %v, want %v", ip, ok, want)
+				}
+			}
+		})
+	}
+}
+
+// 缓存命中时 Lookup 不发起查询并计入命中次数
+func TestLookupCached(t *testing.T) {
+	r := New("", time.Second, time.Hour, 10)
+	want := &model.ReverseDNS{Hostnames: []model.PTRRecord{{Hostname: "dns.google", ForwardConfirmed: true}}}
+	r.store("8.8.8.8", want)
+
+	if got := r.Lookup(context.Background(), "8.8.8.8"); got != want {
+		t.Errorf("Lookup = %+v, want the cached result", got)
+	}
+	if hits, misses := r.Stats(); hits != 1 || misses != 0 {
+		t.Errorf("hits %d, misses %d", hits, misses)
+	}
+}
@@ -3,6 +3,7 @@ package common
 type ResponseResult struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
+	Error   string      `json:"error,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
@@ -13,3 +14,14 @@ func NewResponseResult(code int, message string, data interface{}) ResponseResul
 		Data:    data,
 	}
 }
+
+// ProblemDetails RFC 7807 application/problem+json 响应体
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      int    `json:"code"`
+	RequestId string `json:"request_id,omitempty"`
+}
@@ -0,0 +1,107 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"go-geoip/common"
+	"go-geoip/common/iptrie"
+	logger "go-geoip/common/loggger"
+)
+
+// Reload 重新加载所有公开列表; 某类别任一来源失败时保留该类别上一次的数据
+func (l *Lists) Reload(ctx context.Context) {
+	next := listSet{}
+	previous := l.current.Load()
+
+	for category, sources := range l.sources {
+		if len(sources) == 0 {
+			continue
+		}
+		trie, err := loadCategory(ctx, sources)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("Failed to load %s list: %v", category, err))
+			if previous != nil {
+				next[category] = (*previous)[category]
+			}
+			continue
+		}
+		logger.SysLog(fmt.Sprintf("Loaded %s list with %d prefixes", category, trie.Len()))
+		next[category] = trie
+	}
+
+	l.current.Store(&next)
+}
+
+func loadCategory(ctx context.Context, sources []string) (*iptrie.Trie[struct{}], error) {
+	trie := &iptrie.Trie[struct{}]{}
+	for _, src := range sources {
+		data, err := common.ReadSource(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		for _, prefix := range parsePrefixes(data) {
+			trie.Insert(prefix, struct{}{})
+		}
+	}
+	return trie, nil
+}
+
+// parsePrefixes 解析列表内容: JSON 文件 (如云厂商 ip-ranges.json) 提取所有 IP/CIDR 字符串,
+// 文本文件逐行取第一个可解析的字段 (兼容 Tor 的 torbulkexitlist 与 exit-addresses 格式), # 之后为注释
+func parsePrefixes(data []byte) []netip.Prefix {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var doc any
+		if err := json.Unmarshal(trimmed, &doc); err == nil {
+			var prefixes []netip.Prefix
+			collectJSON(doc, &prefixes)
+			return prefixes
+		}
+	}
+
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, field := range strings.Fields(line) {
+			if prefix, ok := parsePrefix(field); ok {
+				prefixes = append(prefixes, prefix)
+				break
+			}
+		}
+	}
+	return prefixes
+}
+
+func collectJSON(v any, prefixes *[]netip.Prefix) {
+	switch v := v.(type) {
+	case string:
+		if prefix, ok := parsePrefix(v); ok {
+			*prefixes = append(*prefixes, prefix)
+		}
+	case []any:
+		for _, item := range v {
+			collectJSON(item, prefixes)
+		}
+	case map[string]any:
+		for _, item := range v {
+			collectJSON(item, prefixes)
+		}
+	}
+}
+
+func parsePrefix(s string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix, true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
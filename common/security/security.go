@@ -0,0 +1,90 @@
+package security
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+
+	"go-geoip/common/config"
+	"go-geoip/common/iptrie"
+	"go-geoip/model"
+)
+
+// 公开列表类别
+const (
+	categoryTor     = "tor"
+	categoryVPN     = "vpn"
+	categoryProxy   = "proxy"
+	categoryHosting = "hosting"
+)
+
+// 结果中 Sources 字段使用的来源名称后缀
+const sourceListSuffix = "_list"
+
+type listSet map[string]*iptrie.Trie[struct{}]
+
+// Lists 公开的 Tor 出口、VPN、代理及托管 IP 列表, 作为 geoip.Provider 使用
+type Lists struct {
+	sources map[string][]string
+	current atomic.Pointer[listSet]
+}
+
+// NewLists 各类别的来源为 URL 或本地路径, 需调用 Reload 后才有数据
+func NewLists(tor, vpn, proxy, hosting []string) *Lists {
+	return &Lists{sources: map[string][]string{
+		categoryTor:     tor,
+		categoryVPN:     vpn,
+		categoryProxy:   proxy,
+		categoryHosting: hosting,
+	}}
+}
+
+// FromConfig 按 TOR_EXIT_LIST_SOURCES 等环境变量创建
+func FromConfig() *Lists {
+	return NewLists(config.TorExitListSources, config.VPNListSources, config.ProxyListSources, config.HostingListSources)
+}
+
+// Enabled 是否配置了任一列表
+func (l *Lists) Enabled() bool {
+	for _, sources := range l.sources {
+		if len(sources) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lists) Name() string {
+	return "security_lists"
+}
+
+// Enrich 将列表命中结果合并到 info.Security, 没有 Anonymous-IP 数据库时新建
+func (l *Lists) Enrich(_ context.Context, addr netip.Addr, info *model.IPInfoResponse) {
+	result := info.Security
+	if result == nil {
+		result = &model.Security{Sources: []string{}}
+		info.Security = result
+	}
+
+	if lists := l.current.Load(); lists != nil {
+		for _, category := range []string{categoryTor, categoryVPN, categoryProxy, categoryHosting} {
+			trie := (*lists)[category]
+			if trie == nil || !trie.Contains(addr) {
+				continue
+			}
+			switch category {
+			case categoryTor:
+				result.IsTor = true
+			case categoryVPN:
+				result.IsVPN = true
+			case categoryProxy:
+				result.IsProxy = true
+			case categoryHosting:
+				result.IsHosting = true
+			}
+			result.Sources = append(result.Sources, category+sourceListSuffix)
+		}
+	}
+
+	result.IsAnonymous = result.IsAnonymous || result.IsVPN || result.IsTor || result.IsProxy
+}
@@ -1,9 +1,65 @@
 package common
 
 import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"go-geoip/common/config"
+	"go-geoip/common/helper"
 )
 
+const problemContentType = "application/problem+json"
+
 func SendResponse(c *gin.Context, httpCode int, code int, message string, data interface{}) {
 	c.JSON(httpCode, NewResponseResult(code, message, data))
 }
+
+// SendError 按错误码目录输出错误, 开启 ERROR_FORMAT=problem 或请求方接受 problem+json 时输出 RFC 7807 格式
+func SendError(c *gin.Context, err error) {
+	apiErr := AsAPIError(err)
+	if wantsProblem(c) {
+		c.Render(apiErr.Status, problemRender{ProblemDetails{
+			Type:      "urn:go-geoip:error:" + apiErr.Key,
+			Title:     apiErr.Message,
+			Status:    apiErr.Status,
+			Detail:    apiErr.Detail,
+			Instance:  c.Request.URL.Path,
+			Code:      apiErr.Code,
+			RequestId: c.GetString(helper.RequestIdKey),
+		}})
+		return
+	}
+	c.JSON(apiErr.Status, ResponseResult{
+		Code:    apiErr.Code,
+		Message: apiErr.Error(),
+		Error:   apiErr.Key,
+	})
+}
+
+// AbortWithError 输出错误并中止后续 handler
+func AbortWithError(c *gin.Context, err error) {
+	SendError(c, err)
+	c.Abort()
+}
+
+func wantsProblem(c *gin.Context) bool {
+	if config.ErrorFormat == "problem" {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), problemContentType)
+}
+
+type problemRender struct {
+	problem ProblemDetails
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", problemContentType)
+}
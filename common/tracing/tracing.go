@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go-geoip/common"
+	"go-geoip/common/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 本服务创建的 span 所属的 instrumentation scope
+const instrumentationName = "go-geoip"
+
+var (
+	mutex    sync.Mutex
+	provider *sdktrace.TracerProvider
+)
+
+// Setup 设置 W3C traceparent/baggage 传播器; 配置了 OTEL_EXPORTER_OTLP_ENDPOINT 时通过 OTLP/HTTP 批量导出 span,
+// 否则保留默认的 noop TracerProvider, 只透传上游的链路信息
+func Setup(ctx context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.OTLPEndpoint == "" {
+		return nil
+	}
+
+	// 地址、请求头、超时等由导出器按 OTEL_EXPORTER_OTLP_* 读取
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(config.OTelServiceName), semconv.ServiceVersion(common.Version)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return err
+	}
+	SetProvider(sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)))
+	return nil
+}
+
+// SetProvider 替换全局的 TracerProvider, 如使用 tracetest 的内存 exporter 检查产生的 span
+func SetProvider(tp *sdktrace.TracerProvider) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	provider = tp
+	otel.SetTracerProvider(tp)
+}
+
+// Shutdown 导出缓冲中尚未发送的 span, 退出前调用
+func Shutdown(ctx context.Context) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Tracer 返回全局 TracerProvider 的 tracer, Setup 之前取得的 tracer 在 Setup 之后同样生效
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/asnindex"
+	"go-geoip/common/config"
+	"go-geoip/model"
+)
+
+// ASN查询
+// @Summary ASN查询
+// @Description 返回 AS 号对应的组织名称、ASN 数据库中分配给它的所有前缀及覆盖的地址数 (需要 `asn` 权限的密钥)
+// @Tags ASN查询
+// @Produce json
+// @Param number path string true "AS 号, 如 13335 或 AS13335"
+// @Success 200 {object} model.ASNInfo "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 404 {object} common.ResponseResult "not_found"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /asn/{number} [get]
+func Asn(c *gin.Context) {
+	value := c.Param("number")
+	number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 32)
+	if err != nil {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("AS number %q", value))
+		return
+	}
+
+	index, err := getASNIndex()
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	info, ok := index.Get(uint(number))
+	if !ok {
+		common.SendError(c, common.ErrNotFound.WithDetail("AS%d", number))
+		return
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", info)
+}
+
+// ASN搜索
+// @Summary ASN搜索
+// @Description 按组织名称子串(不区分大小写)搜索 AS, 结果按 AS 号排序 (需要 `asn` 权限的密钥)
+// @Tags ASN查询
+// @Produce json
+// @Param org query string true "组织名称子串"
+// @Param offset query int false "偏移量"
+// @Param limit query int false "每页条数, 不超过 NETWORK_MAX_LIMIT"
+// @Success 200 {object} model.ASNSearchResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /asn [get]
+func AsnSearch(c *gin.Context) {
+	org := strings.TrimSpace(c.Query("org"))
+	if org == "" {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("org is required"))
+		return
+	}
+	offset, limit, err := parsePagination(c, config.NetworkDefaultLimit, config.NetworkMaxLimit)
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+
+	index, err := getASNIndex()
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	matches := index.Search(org)
+
+	result := &model.ASNSearchResponse{
+		Org:     org,
+		Total:   len(matches),
+		Offset:  offset,
+		Limit:   limit,
+		Results: []model.ASNSummary{},
+	}
+	for i := offset; i < len(matches) && i < offset+limit; i++ {
+		info := matches[i]
+		result.Results = append(result.Results, model.ASNSummary{
+			Number:        info.Number,
+			Organization:  info.Organization,
+			PrefixCount:   len(info.IPv4Prefixes) + len(info.IPv6Prefixes),
+			IPv4Addresses: info.IPv4Addresses,
+			IPv6Slash64s:  info.IPv6Slash64s,
+		})
+	}
+	if offset+limit < len(matches) {
+		next := offset + limit
+		result.NextOffset = &next
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", result)
+}
+
+func getASNIndex() (*asnindex.Index, error) {
+	e, err := getEngine()
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.ASNIndex()
+	if err != nil {
+		return nil, toAPIError(err, "")
+	}
+	if index == nil {
+		return nil, common.ErrDBUnavailable
+	}
+	return index, nil
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/config"
+	"go-geoip/model"
+)
+
+// 批量IP查询
+// @Summary 批量IP查询
+// @Description 一次查询多个 IP, 单个 IP 失败不影响其他结果; 数量上限由 BATCH_MAX_IPS 配置 (需要 `batch` 权限的密钥)
+// @Tags IP查询
+// @Accept json
+// @Produce json
+// @Param body body model.BatchRequest true "IP 列表"
+// @Param rdns query bool false "返回 PTR 反向解析结果"
+// @Param strict query bool false "严格模式: 所有数据库均未命中的 IP 返回 not_found"
+// @Success 200 {object} model.BatchResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /ip/batch [post]
+func IpBatch(c *gin.Context) {
+	var req model.BatchRequest
+	if err := bindBatchRequest(c, &req); err != nil {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("%s", err.Error()))
+		return
+	}
+	if len(req.IPs) > config.BatchMaxIPs {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("at most %d IPs per batch", config.BatchMaxIPs))
+		return
+	}
+
+	opts := getLookupOptions(c)
+	strict := isStrict(c)
+	resp := model.BatchResponse{Results: make([]model.BatchResult, len(req.IPs))}
+	errs := make([]*common.APIError, len(req.IPs))
+
+	// 固定数量的 worker 并发查询, 结果按下标写回以保持请求中的顺序; 启用 rdns 时总耗时约为 IP 数 / worker 数 × RDNS_TIMEOUT
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(config.BatchWorkers, 1), len(req.IPs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp.Results[i], errs[i] = lookupBatchIP(c.Request.Context(), req.IPs[i], opts, strict)
+			}
+		}()
+	}
+	for i := range req.IPs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	// 数据库不可用时整个批次都无法完成
+	for _, apiErr := range errs {
+		if apiErr != nil && apiErr.Key == common.ErrDBUnavailable.Key {
+			common.SendError(c, apiErr)
+			return
+		}
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", resp)
+}
+
+// lookupBatchIP 查询批次中的一个 IP, 失败时错误记录在结果中并同时返回
+func lookupBatchIP(ctx context.Context, ip string, opts LookupOptions, strict bool) (model.BatchResult, *common.APIError) {
+	result := model.BatchResult{IP: ip}
+	info, err := getIpInfo(ctx, ip, opts)
+	if err == nil && strict && !info.Found.Any() {
+		err = common.ErrNotFound.WithDetail("no record for %s", ip)
+	}
+	if err != nil {
+		apiErr := common.AsAPIError(err)
+		result.Error, result.Code, result.Message = apiErr.Key, apiErr.Code, apiErr.Error()
+		return result, apiErr
+	}
+	result.Data = info
+	return result, nil
+}
+
+// BatchCost 批量查询按 IP 数计费, 最多按 BATCH_MAX_IPS 计; 请求体缓存在上下文中, 供 IpBatch 再次解析
+func BatchCost(c *gin.Context) int {
+	var req model.BatchRequest
+	if err := bindBatchRequest(c, &req); err != nil {
+		return 1
+	}
+	return min(len(req.IPs), config.BatchMaxIPs)
+}
+
+// bindBatchRequest 请求体按 BATCH_MAX_IPS 限制大小后再解析, 超出时返回错误而不是读入整个请求体
+func bindBatchRequest(c *gin.Context, req *model.BatchRequest) error {
+	if _, cached := c.Get(gin.BodyBytesKey); !cached {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBodyBytes())
+	}
+	return c.ShouldBindBodyWithJSON(req)
+}
+
+// maxBatchBodyBytes 每个 IP 按不超过 64 字节 (含引号、逗号与空白) 估算, 另加 1KB 余量
+func maxBatchBodyBytes() int64 {
+	return int64(config.BatchMaxIPs)*64 + 1024
+}
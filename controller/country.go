@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/model"
+)
+
+// setNamePattern 集合名/变量名原样写入 nftables、ipset 与 nginx 配置, 只允许字母、数字与下划线, 防止注入其他指令;
+// 不能以数字开头 (nginx 变量名), 加上 _v4/_v6 后缀不超过 ipset 名称的 31 个字符
+var setNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,27}$`)
+
+// 国家网段
+// @Summary 国家网段
+// @Description 遍历 City 数据库, 返回国家的聚合 CIDR 列表(IPv4/IPv6 分开), 支持 nftables、ipset、nginx geo 及纯文本格式 (需要 `country` 权限的密钥)
+// @Tags 国家网段
+// @Produce json,plain
+// @Param iso path string true "ISO 3166-1 国家代码, 如 CN"
+// @Param format query string false "json(默认)/text/nftables/ipset/nginx"
+// @Param family query string false "4/6/all(默认)"
+// @Param name query string false "nftables/ipset 集合名或 nginx 变量名, 1-28 个字母、数字或下划线且不以数字开头, 默认 geoip_<iso>"
+// @Success 200 {object} model.CountryNetworksResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /country/{iso}/networks [get]
+func CountryNetworks(c *gin.Context) {
+	iso := strings.ToUpper(c.Param("iso"))
+	if len(iso) != 2 || strings.Trim(iso, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("country code %q", c.Param("iso")))
+		return
+	}
+	family := c.DefaultQuery("family", "all")
+	if family != "4" && family != "6" && family != "all" {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("family %q", family))
+		return
+	}
+	name := c.DefaultQuery("name", "geoip_"+strings.ToLower(iso))
+	if !setNamePattern.MatchString(name) {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("name %q: 1-28 letters, digits or underscores, not starting with a digit", name))
+		return
+	}
+
+	e, err := getEngine()
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	result, err := e.CountryNetworks(iso)
+	if err != nil {
+		common.SendError(c, toAPIError(err, iso))
+		return
+	}
+	ipv4, ipv6 := result.IPv4, result.IPv6
+	if family == "6" {
+		ipv4 = nil
+	}
+	if family == "4" {
+		ipv6 = nil
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		common.SendResponse(c, http.StatusOK, 0, "success", &model.CountryNetworksResponse{
+			Country:    result.Country,
+			BuildEpoch: result.BuildEpoch,
+			IPv4:       nonNil(ipv4),
+			IPv6:       nonNil(ipv6),
+		})
+	case "text":
+		c.String(http.StatusOK, formatText(ipv4, ipv6))
+	case "nftables":
+		c.String(http.StatusOK, formatNftables(name, ipv4, ipv6))
+	case "ipset":
+		c.String(http.StatusOK, formatIpset(name, ipv4, ipv6))
+	case "nginx":
+		c.String(http.StatusOK, formatNginxGeo(name, ipv4, ipv6))
+	default:
+		common.SendError(c, common.ErrInvalidParam.WithDetail("format %q", format))
+	}
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func formatText(ipv4, ipv6 []string) string {
+	var b strings.Builder
+	for _, cidr := range append(ipv4, ipv6...) {
+		b.WriteString(cidr + "\n")
+	}
+	return b.String()
+}
+
+func formatNftables(name string, ipv4, ipv6 []string) string {
+	var b strings.Builder
+	writeSet := func(suffix, typ string, cidrs []string) {
+		if cidrs == nil {
+			return
+		}
+		fmt.Fprintf(&b, "set %s_%s {\n\ttype %s\n\tflags interval\n", name, suffix, typ)
+		if len(cidrs) > 0 {
+			fmt.Fprintf(&b, "\telements = {\n\t\t%s\n\t}\n", strings.Join(cidrs, ",\n\t\t"))
+		}
+		b.WriteString("}\n")
+	}
+	writeSet("v4", "ipv4_addr", ipv4)
+	writeSet("v6", "ipv6_addr", ipv6)
+	return b.String()
+}
+
+func formatIpset(name string, ipv4, ipv6 []string) string {
+	var b strings.Builder
+	writeSet := func(suffix, family string, cidrs []string) {
+		if cidrs == nil {
+			return
+		}
+		fmt.Fprintf(&b, "create %s_%s hash:net family %s -exist\n", name, suffix, family)
+		for _, cidr := range cidrs {
+			fmt.Fprintf(&b, "add %s_%s %s -exist\n", name, suffix, cidr)
+		}
+	}
+	writeSet("v4", "inet", ipv4)
+	writeSet("v6", "inet6", ipv6)
+	return b.String()
+}
+
+func formatNginxGeo(name string, ipv4, ipv6 []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "geo $%s {\n\tdefault 0;\n", name)
+	for _, cidr := range append(ipv4, ipv6...) {
+		fmt.Fprintf(&b, "\t%s 1;\n", cidr)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/config"
+	"go-geoip/geoip"
+	"go-geoip/model"
+)
+
+// dualStackScript 定义 goGeoipDualStack(), 分别请求两个地址族的 /ip, 返回 Promise<{ipv4, ipv6}>, 失败或未配置的一方为 null
+const dualStackScript = `(function (global) {
+	var urls = %s;
+	function self(base) {
+		if (!base) return Promise.resolve(null);
+		return fetch(base + "/ip", {cache: "no-store"})
+			.then(function (r) { return r.ok ? r.json() : null; })
+			.then(function (body) { return body ? body.data : null; })
+			.catch(function () { return null; });
+	}
+	global.goGeoipDualStack = function () {
+		return Promise.all([self(urls.ipv4_url), self(urls.ipv6_url)]).then(function (r) {
+			return {ipv4: r[0], ipv6: r[1]};
+		});
+	};
+})(typeof window !== "undefined" ? window : this);
+`
+
+// 双栈自助查询
+// @Summary 双栈自助查询
+// @Description 返回仅 IPv4 与仅 IPv6 的基础 URL (IPV4_BASE_URL/IPV6_BASE_URL), 浏览器分别请求其 /ip 即可同时获得访问者的 IPv4 与 IPv6 地址
+// @Tags IP查询
+// @Produce json
+// @Success 200 {object} model.DualStackResponse "Successful response"
+// @Router /dualstack [get]
+func DualStack(c *gin.Context) {
+	common.SendResponse(c, http.StatusOK, 0, "success", newDualStackResponse(c))
+}
+
+// 双栈自助查询脚本
+// @Summary 双栈自助查询脚本
+// @Description 返回定义 goGeoipDualStack() 的脚本, 调用后得到 Promise<{ipv4, ipv6}>, 值为各地址族 /ip 的查询结果, 失败或未配置时为 null
+// @Tags IP查询
+// @Produce application/javascript
+// @Success 200 {string} string "JavaScript"
+// @Router /dualstack.js [get]
+func DualStackJS(c *gin.Context) {
+	urls, _ := json.Marshal(newDualStackResponse(c))
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(fmt.Sprintf(dualStackScript, urls)))
+}
+
+func newDualStackResponse(c *gin.Context) *model.DualStackResponse {
+	ip := getRealClientIP(c)
+	resp := &model.DualStackResponse{
+		IP:      ip,
+		IPv4URL: strings.TrimRight(config.IPv4BaseURL, "/"),
+		IPv6URL: strings.TrimRight(config.IPv6BaseURL, "/"),
+	}
+	if addr, err := geoip.ParseAddr(ip, false); err == nil {
+		resp.IP = addr.String()
+		resp.Version = geoip.Version(addr)
+	}
+	return resp
+}
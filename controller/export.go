@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	logger "go-geoip/common/loggger"
+	"go-geoip/geoip"
+)
+
+// 数据导出
+// @Summary 数据导出
+// @Description 流式导出 City 数据库的所有网段, 合并 ASN 与 GeoCN 数据(合并规则与 IP 查询一致), 网段按 ASN/GeoCN 的边界拆分 (需要 `export` 权限的密钥)
+// @Tags 数据导出
+// @Produce plain
+// @Param format query string false "csv(默认)/jsonl"
+// @Success 200 {string} string "CSV 或 JSONL 数据流"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /export [get]
+func Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if !containsString(geoip.ExportFormats, format) {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("format %q", format))
+		return
+	}
+	e, err := getEngine()
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	dbs, err := e.Acquire()
+	if err != nil {
+		common.SendError(c, toAPIError(err, ""))
+		return
+	}
+	buildEpoch := dbs.City.Metadata.BuildEpoch
+	dbs.Release()
+
+	contentType := "text/csv; charset=utf-8"
+	if format == "jsonl" {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=go-geoip-%d.%s", buildEpoch, format))
+	c.Status(http.StatusOK)
+
+	// 响应头已发出, 之后的错误只能记录日志
+	if err := e.Export(c.Request.Context(), c.Writer, format); err != nil {
+		logger.Errorf(c.Request.Context(), "export failed: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/model"
+)
+
+// 服务信息
+// @Summary 服务信息
+// @Description 返回服务版本及当前已加载数据库的元数据 (需要任意有效密钥)
+// @Tags 服务信息
+// @Produce json
+// @Success 200 {object} model.ServiceInfo "Successful response"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /info [get]
+func Info(c *gin.Context) {
+	info := model.ServiceInfo{Version: common.Version, Databases: []model.DatabaseInfo{}}
+	if e := engine.Load(); e != nil {
+		info.Databases = e.Info()
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", info)
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/apikey"
+	"go-geoip/model"
+)
+
+// 密钥列表
+// @Summary 密钥列表
+// @Description 列出全部 API 密钥(含已吊销), 不返回密钥明文与哈希 (需要 `admin` 权限的密钥, 始终需要)
+// @Tags 密钥管理
+// @Produce json
+// @Success 200 {array} model.APIKey "Successful response"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Router /admin/keys [get]
+func ListKeys(c *gin.Context) {
+	store, _ := apikey.Default()
+	keys := []model.APIKey{}
+	now := time.Now()
+	for _, k := range store.List() {
+		keys = append(keys, newAPIKey(&k, now))
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", keys)
+}
+
+// 创建密钥
+// @Summary 创建密钥
+// @Description 创建 API 密钥, 明文仅在响应中返回一次 (需要 `admin` 权限的密钥, 始终需要)
+// @Tags 密钥管理
+// @Accept json
+// @Produce json
+// @Param body body model.APIKeyCreateRequest true "密钥属性"
+// @Success 200 {object} model.APIKeySecretResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Router /admin/keys [post]
+func CreateKey(c *gin.Context) {
+	var req model.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("%s", err.Error()))
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !containsString(apikey.Scopes, scope) {
+			common.SendError(c, common.ErrInvalidParam.WithDetail("unknown scope %q", scope))
+			return
+		}
+	}
+	if req.RateLimit < 0 || req.DailyQuota < 0 {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("rate_limit and daily_quota must not be negative"))
+		return
+	}
+
+	store, _ := apikey.Default()
+	key, secret, err := store.Create(apikey.Spec{
+		Label:      req.Label,
+		Scopes:     req.Scopes,
+		RateLimit:  req.RateLimit,
+		DailyQuota: req.DailyQuota,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", model.APIKeySecretResponse{Key: newAPIKey(key, time.Now()), Secret: secret})
+}
+
+// 轮换密钥
+// @Summary 轮换密钥
+// @Description 为已有密钥生成新的明文, 旧明文立即失效, 其余属性不变 (需要 `admin` 权限的密钥, 始终需要)
+// @Tags 密钥管理
+// @Produce json
+// @Param id path string true "密钥 ID"
+// @Success 200 {object} model.APIKeySecretResponse "Successful response"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Failure 404 {object} common.ResponseResult "not_found"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Router /admin/keys/{id}/rotate [post]
+func RotateKey(c *gin.Context) {
+	store, _ := apikey.Default()
+	key, secret, err := store.Rotate(c.Param("id"))
+	if err != nil {
+		common.SendError(c, keyError(err, c.Param("id")))
+		return
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", model.APIKeySecretResponse{Key: newAPIKey(key, time.Now()), Secret: secret})
+}
+
+// 吊销密钥
+// @Summary 吊销密钥
+// @Description 吊销密钥, 立即生效; 记录保留在列表中 (需要 `admin` 权限的密钥, 始终需要)
+// @Tags 密钥管理
+// @Produce json
+// @Param id path string true "密钥 ID"
+// @Success 200 {object} model.APIKey "Successful response"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Failure 404 {object} common.ResponseResult "not_found"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Router /admin/keys/{id} [delete]
+func RevokeKey(c *gin.Context) {
+	store, _ := apikey.Default()
+	key, err := store.Revoke(c.Param("id"))
+	if err != nil {
+		common.SendError(c, keyError(err, c.Param("id")))
+		return
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", newAPIKey(key, time.Now()))
+}
+
+func keyError(err error, id string) error {
+	switch {
+	case errors.Is(err, apikey.ErrKeyNotFound):
+		return common.ErrNotFound.WithDetail("key %s", id)
+	case errors.Is(err, apikey.ErrRevoked):
+		return common.ErrInvalidParam.WithDetail("key %s is revoked", id)
+	}
+	return err
+}
+
+func newAPIKey(k *apikey.Key, now time.Time) model.APIKey {
+	return model.APIKey{
+		ID:         k.ID,
+		Label:      k.Label,
+		Scopes:     k.Scopes,
+		RateLimit:  k.RateLimit,
+		DailyQuota: k.DailyQuota,
+		Active:     k.Active(now),
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		RotatedAt:  k.RotatedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
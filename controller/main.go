@@ -1,23 +1,39 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go-geoip/common"
+	"go-geoip/common/config"
 	logger "go-geoip/common/loggger"
+	"go-geoip/geoip"
 	"go-geoip/model"
-	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
 )
 
 // IP查询
 // @Summary IP查询
-// @Description IP查询
+// @Description IP查询 (需要 `lookup` 权限的密钥)
 // @Tags IP查询
 // @Produce json
 // @Param ip path string true "IP address"
+// @Param rdns query bool false "返回 PTR 反向解析结果"
+// @Param strict query bool false "严格模式: 所有数据库均未命中时返回 404"
 // @Success 200 {object} model.IPInfoResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_ip / reserved_address"
+// @Failure 404 {object} common.ResponseResult "not_found (strict)"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Failure 429 {object} common.ResponseResult "rate_limited"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
 // @Router /ip/{ip} [get]
 func Ip(c *gin.Context) {
 	ip := c.Param("ip")
@@ -27,20 +43,58 @@ func Ip(c *gin.Context) {
 	handleIpInfoResponse(c, ip)
 }
 
+// 查询本机IP
+// @Summary 查询本机IP
+// @Description 查询请求方自身的 IP, 无需密钥
+// @Tags IP查询
+// @Produce json
+// @Param rdns query bool false "返回 PTR 反向解析结果"
+// @Param strict query bool false "严格模式: 所有数据库均未命中时返回 404"
+// @Success 200 {object} model.IPInfoResponse "Successful response"
+// @Failure 404 {object} common.ResponseResult "not_found (strict)"
+// @Failure 429 {object} common.ResponseResult "rate_limited"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Router /ip [get]
 func IpNoArgs(c *gin.Context) {
 	ip := getRealClientIP(c)
 	handleIpInfoResponse(c, ip)
 }
 
+// LookupOptions 控制 getIpInfo 的可选增强项
+type LookupOptions struct {
+	RDNS bool
+}
+
+func getLookupOptions(c *gin.Context) LookupOptions {
+	return LookupOptions{
+		RDNS: isTrue(c.Query("rdns")),
+	}
+}
+
+func isTrue(v string) bool {
+	return v == "1" || v == "true"
+}
+
 func handleIpInfoResponse(c *gin.Context, ip string) {
-	info, err := getIpInfo(ip)
+	info, err := getIpInfo(c.Request.Context(), ip, getLookupOptions(c))
 	if err != nil {
-		common.SendResponse(c, http.StatusInternalServerError, 1, "error", err.Error())
+		common.SendError(c, err)
+		return
+	}
+	if isStrict(c) && !info.Found.Any() {
+		common.SendError(c, common.ErrNotFound.WithDetail("no record for %s", ip))
 		return
 	}
 	common.SendResponse(c, http.StatusOK, 0, "success", info)
 }
 
+func isStrict(c *gin.Context) bool {
+	if strict, ok := c.GetQuery("strict"); ok {
+		return isTrue(strict)
+	}
+	return config.StrictLookup
+}
+
 func getRealClientIP(c *gin.Context) string {
 	if xff := c.GetHeader("X-Forwarded-For"); xff != "" {
 		ips := strings.Split(xff, ",")
@@ -59,110 +113,58 @@ func getRealClientIP(c *gin.Context) string {
 	return clientIP
 }
 
-func getIpInfo(ip string) (*model.IPInfoResponse, error) {
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return nil, fmt.Errorf("invalid IP address")
-	}
-
-	info := &model.IPInfoResponse{IP: ip}
+// engine 由 SetEngine 在首次加载数据库后设置
+var engine atomic.Pointer[geoip.Engine]
 
-	common.Mu.Lock()
-	defer common.Mu.Unlock()
-
-	if err := populateASInfo(parsedIP, info); err != nil {
-		return nil, err
-	}
-
-	if err := populateCityInfo(parsedIP, info); err != nil {
-		return nil, err
-	}
-
-	if info.Country == "中国" {
-		populateCnInfo(ip, info)
-	}
-
-	return info, nil
+// SetEngine 设置查询使用的引擎, 设置前所有查询返回 db_unavailable
+func SetEngine(e *geoip.Engine) {
+	engine.Store(e)
 }
 
-func populateASInfo(parsedIP net.IP, info *model.IPInfoResponse) error {
-	var asn model.ASN
-	if err := common.AsnReader.Lookup(parsedIP, &asn); err != nil {
-		return err
+func getEngine() (*geoip.Engine, error) {
+	if e := engine.Load(); e != nil {
+		return e, nil
 	}
-	info.AS = asn.Organization
-	return nil
+	return nil, common.ErrDBUnavailable
 }
 
-func populateCityInfo(parsedIP net.IP, info *model.IPInfoResponse) error {
-	var city model.City
-	if network, ok, err := common.CityReader.LookupNetwork(parsedIP, &city); err == nil && ok {
-		info.Addr = network.String()
-		info.Country = getCountry(city.Country.Names)
-		info.RegisteredCountry = getCountry(city.RegisteredCountry.Names)
-		info.Latitude = city.Location.Latitude
-		info.Longitude = city.Location.Longitude
-		info.Subdivisions = getSubdivisions(city.Subdivisions)
-		info.City = getCityName(city.City.Names)
-	} else if err != nil {
-		return err
-	}
-	return nil
+// LookupIP 与 /ip/{ip} 接口相同的查询逻辑, 供命令行等非 HTTP 场景使用
+func LookupIP(ctx context.Context, ip string, opts LookupOptions) (*model.IPInfoResponse, error) {
+	return getIpInfo(ctx, ip, opts)
 }
 
-func populateCnInfo(ip string, info *model.IPInfoResponse) {
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return
+func getIpInfo(ctx context.Context, ip string, opts LookupOptions) (*model.IPInfoResponse, error) {
+	addr, err := geoip.ParseAddr(ip, config.StrictIPParsing)
+	if err != nil {
+		return nil, common.ErrInvalidIP.WithDetail("%s", ip)
 	}
-
-	var geoCN model.GeoCN
-	if network, ok, err := common.CnReader.LookupNetwork(parsedIP, &geoCN); err == nil && ok {
-		info.Addr = network.String()
-		if strings.HasSuffix(geoCN.Province, "市") {
-			info.Province = geoCN.Province
-			info.City = geoCN.Province
-			info.District = geoCN.City
-		} else {
-			info.Province = geoCN.Province
-			info.City = geoCN.City
-			info.District = geoCN.Districts
-		}
-
-		info.AS = geoCN.ISP
-		if geoCN.Net != "" {
-			info.AS += " (" + geoCN.Net + ")"
-		}
+	e, err := getEngine()
+	if err != nil {
+		return nil, err
 	}
-}
 
-func getSubdivisions(subdivisions []model.Subdivision) []string {
-	var names []string
-	for _, subdivision := range subdivisions {
-		if name, exists := subdivision.Names["zh-CN"]; exists {
-			names = append(names, name)
-		} else {
-			names = append(names, subdivision.Names["en"])
-		}
+	var lookupOpts []geoip.LookupOption
+	if opts.RDNS {
+		lookupOpts = append(lookupOpts, geoip.WithRDNS())
 	}
-	return names
-}
-
-func getCityName(names map[string]string) string {
-	if name, exists := names["zh-CN"]; exists {
-		return name
+	info, err := e.Lookup(ctx, addr, lookupOpts...)
+	if err != nil {
+		return nil, toAPIError(err, ip)
 	}
-	return names["en"]
+	return info, nil
 }
 
-func getCountry(names map[string]string) string {
-	if name, ok := names["zh-CN"]; ok {
-		switch name {
-		case "香港", "澳门", "台湾":
-			return "中国" + name
-		default:
-			return name
-		}
+// toAPIError 将 geoip 包的错误映射到错误码目录
+func toAPIError(err error, detail string) error {
+	switch {
+	case errors.Is(err, geoip.ErrInvalidAddr):
+		return common.ErrInvalidIP.WithDetail("%s", detail)
+	case errors.Is(err, geoip.ErrReservedAddress):
+		return common.ErrReservedAddress.WithDetail("%s", detail)
+	case errors.Is(err, geoip.ErrUnknownDatabase), errors.Is(err, geoip.ErrUnknownFormat):
+		return common.ErrInvalidParam.WithDetail("%s", detail)
+	case errors.Is(err, geoip.ErrClosed):
+		return common.ErrDBUnavailable
 	}
-	return names["en"]
+	return err
 }
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/config"
+	"go-geoip/geoip"
+)
+
+// 网段查询
+// @Summary 网段查询
+// @Description 列出网段内 City/ASN/GeoCN 数据库中的所有子网及其记录, 按数据库、网段顺序分页返回 (需要 `network` 权限的密钥)
+// @Tags 网段查询
+// @Produce json
+// @Param cidr path string true "CIDR, 如 203.0.113.0/22"
+// @Param db query string false "逗号分隔的数据库: city,asn,cn (默认全部)"
+// @Param offset query int false "偏移量, 不超过 NETWORK_MAX_OFFSET"
+// @Param limit query int false "每页条数, 不超过 NETWORK_MAX_LIMIT"
+// @Success 200 {object} model.NetworkListResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_network / invalid_parameter"
+// @Failure 503 {object} common.ResponseResult "db_unavailable"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /network/{cidr} [get]
+func Network(c *gin.Context) {
+	cidr := strings.TrimPrefix(c.Param("cidr"), "/")
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		common.SendError(c, common.ErrInvalidNetwork.WithDetail("%s", cidr))
+		return
+	}
+
+	databases, err := parseDatabases(c.Query("db"))
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	offset, limit, err := parsePagination(c, config.NetworkDefaultLimit, config.NetworkMaxLimit)
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	// 每页都从头跳过 offset 个网段, 限制偏移量避免单个请求遍历整个数据库
+	if offset > config.NetworkMaxOffset {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("offset %d exceeds %d, query a smaller network", offset, config.NetworkMaxOffset))
+		return
+	}
+
+	e, err := getEngine()
+	if err != nil {
+		common.SendError(c, err)
+		return
+	}
+	result, err := e.Networks(prefix, databases, offset, limit)
+	if err != nil {
+		common.SendError(c, toAPIError(err, cidr))
+		return
+	}
+	common.SendResponse(c, http.StatusOK, 0, "success", result)
+}
+
+func parseDatabases(value string) ([]string, error) {
+	if value == "" {
+		return geoip.NetworkDatabases, nil
+	}
+	var databases []string
+	for _, db := range strings.Split(value, ",") {
+		db = strings.TrimSpace(db)
+		if !containsString(geoip.NetworkDatabases, db) {
+			return nil, common.ErrInvalidParam.WithDetail("unknown database %q", db)
+		}
+		databases = append(databases, db)
+	}
+	return databases, nil
+}
+
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (offset, limit int, err error) {
+	offset, limit = 0, defaultLimit
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, common.ErrInvalidParam.WithDetail("offset %q", v)
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, common.ErrInvalidParam.WithDetail("limit %q", v)
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return offset, limit, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
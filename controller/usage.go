@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common"
+	"go-geoip/common/apikey"
+	"go-geoip/middleware"
+	"go-geoip/model"
+)
+
+// 用量查询
+// @Summary 用量查询
+// @Description 返回当前密钥按月累计的请求数与计费单位(批量查询按 IP 数计)及今日配额使用情况; admin 密钥可通过 key_id 查询其他密钥 (需要任意有效密钥)
+// @Tags 密钥管理
+// @Produce json
+// @Param month query string false "UTC 月份, 如 2026-10, 默认当月"
+// @Param key_id query string false "要查询的密钥 ID, 仅 admin 可用"
+// @Success 200 {object} model.UsageResponse "Successful response"
+// @Failure 400 {object} common.ResponseResult "invalid_parameter"
+// @Failure 401 {object} common.ResponseResult "unauthorized"
+// @Failure 403 {object} common.ResponseResult "forbidden"
+// @Security BearerAuth
+// @Security ApiKeyHeader
+// @Security ApiKeyQuery
+// @Security HMACSignature
+// @Router /usage [get]
+func Usage(c *gin.Context) {
+	key := middleware.CurrentKey(c)
+	if key == nil {
+		common.SendError(c, common.ErrUnauthorized.WithDetail("API key required"))
+		return
+	}
+
+	month := c.DefaultQuery("month", time.Now().UTC().Format(apikey.MonthFormat))
+	if _, err := time.Parse(apikey.MonthFormat, month); err != nil {
+		common.SendError(c, common.ErrInvalidParam.WithDetail("month must be YYYY-MM"))
+		return
+	}
+
+	id, dailyQuota := key.ID, key.DailyQuota
+	if keyID := c.Query("key_id"); keyID != "" && keyID != key.ID {
+		if !key.HasScope(apikey.ScopeAdmin) {
+			common.SendError(c, common.ErrForbidden.WithDetail("scope %q required", apikey.ScopeAdmin))
+			return
+		}
+		id, dailyQuota = keyID, 0
+		store, _ := apikey.Default()
+		for _, k := range store.List() {
+			if k.ID == keyID {
+				dailyQuota = k.DailyQuota
+			}
+		}
+	}
+
+	ledger, _ := apikey.DefaultLedger()
+	store, _ := apikey.Default()
+	usage := ledger.Get(id, month)
+	common.SendResponse(c, http.StatusOK, 0, "success", model.UsageResponse{
+		KeyID:      id,
+		Month:      month,
+		Requests:   usage.Requests,
+		Units:      usage.Units,
+		Routes:     usage.Routes,
+		DailyQuota: dailyQuota,
+		DailyUsed:  store.DailyUsage(id),
+	})
+}
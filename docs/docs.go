@@ -15,9 +15,766 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "列出全部 API 密钥(含已吊销), 不返回密钥明文与哈希 (需要 ` + "`" + `admin` + "`" + ` 权限的密钥, 始终需要)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "密钥管理"
+                ],
+                "summary": "密钥列表",
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "创建 API 密钥, 明文仅在响应中返回一次 (需要 ` + "`" + `admin` + "`" + ` 权限的密钥, 始终需要)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "密钥管理"
+                ],
+                "summary": "创建密钥",
+                "parameters": [
+                    {
+                        "description": "密钥属性",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeyCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeySecretResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "吊销密钥, 立即生效; 记录保留在列表中 (需要 ` + "`" + `admin` + "`" + ` 权限的密钥, 始终需要)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "密钥管理"
+                ],
+                "summary": "吊销密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "密钥 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKey"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    }
+                ],
+                "description": "为已有密钥生成新的明文, 旧明文立即失效, 其余属性不变 (需要 ` + "`" + `admin` + "`" + ` 权限的密钥, 始终需要)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "密钥管理"
+                ],
+                "summary": "轮换密钥",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "密钥 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeySecretResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/asn": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "按组织名称子串(不区分大小写)搜索 AS, 结果按 AS 号排序 (需要 ` + "`" + `asn` + "`" + ` 权限的密钥)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ASN查询"
+                ],
+                "summary": "ASN搜索",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "组织名称子串",
+                        "name": "org",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "偏移量",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数, 不超过 NETWORK_MAX_LIMIT",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.ASNSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/asn/{number}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "返回 AS 号对应的组织名称、ASN 数据库中分配给它的所有前缀及覆盖的地址数 (需要 ` + "`" + `asn` + "`" + ` 权限的密钥)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ASN查询"
+                ],
+                "summary": "ASN查询",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "AS 号, 如 13335 或 AS13335",
+                        "name": "number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.ASNInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/country/{iso}/networks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "遍历 City 数据库, 返回国家的聚合 CIDR 列表(IPv4/IPv6 分开), 支持 nftables、ipset、nginx geo 及纯文本格式 (需要 ` + "`" + `country` + "`" + ` 权限的密钥)",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "国家网段"
+                ],
+                "summary": "国家网段",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 国家代码, 如 CN",
+                        "name": "iso",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json(默认)/text/nftables/ipset/nginx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "4/6/all(默认)",
+                        "name": "family",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nftables/ipset 集合名或 nginx 变量名, 1-28 个字母、数字或下划线且不以数字开头, 默认 geoip_\u003ciso\u003e",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.CountryNetworksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/dualstack": {
+            "get": {
+                "description": "返回仅 IPv4 与仅 IPv6 的基础 URL (IPV4_BASE_URL/IPV6_BASE_URL), 浏览器分别请求其 /ip 即可同时获得访问者的 IPv4 与 IPv6 地址",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP查询"
+                ],
+                "summary": "双栈自助查询",
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.DualStackResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dualstack.js": {
+            "get": {
+                "description": "返回定义 goGeoipDualStack() 的脚本, 调用后得到 Promise\u003c{ipv4, ipv6}\u003e, 值为各地址族 /ip 的查询结果, 失败或未配置时为 null",
+                "produces": [
+                    "application/javascript"
+                ],
+                "tags": [
+                    "IP查询"
+                ],
+                "summary": "双栈自助查询脚本",
+                "responses": {
+                    "200": {
+                        "description": "JavaScript",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "流式导出 City 数据库的所有网段, 合并 ASN 与 GeoCN 数据(合并规则与 IP 查询一致), 网段按 ASN/GeoCN 的边界拆分 (需要 ` + "`" + `export` + "`" + ` 权限的密钥)",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "数据导出"
+                ],
+                "summary": "数据导出",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv(默认)/jsonl",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV 或 JSONL 数据流",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/info": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "返回服务版本及当前已加载数据库的元数据 (需要任意有效密钥)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务信息"
+                ],
+                "summary": "服务信息",
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServiceInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/ip": {
+            "get": {
+                "description": "查询请求方自身的 IP, 无需密钥",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP查询"
+                ],
+                "summary": "查询本机IP",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "返回 PTR 反向解析结果",
+                        "name": "rdns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "严格模式: 所有数据库均未命中时返回 404",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.IPInfoResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found (strict)",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "429": {
+                        "description": "rate_limited",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/ip/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "一次查询多个 IP, 单个 IP 失败不影响其他结果; 数量上限由 BATCH_MAX_IPS 配置 (需要 ` + "`" + `batch` + "`" + ` 权限的密钥)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP查询"
+                ],
+                "summary": "批量IP查询",
+                "parameters": [
+                    {
+                        "description": "IP 列表",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "返回 PTR 反向解析结果",
+                        "name": "rdns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "严格模式: 所有数据库均未命中的 IP 返回 not_found",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
         "/ip/{ip}": {
             "get": {
-                "description": "IP查询",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "IP查询 (需要 ` + "`" + `lookup` + "`" + ` 权限的密钥)",
                 "produces": [
                     "application/json"
                 ],
@@ -32,61 +789,771 @@ const docTemplate = `{
                         "name": "ip",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "返回 PTR 反向解析结果",
+                        "name": "rdns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "严格模式: 所有数据库均未命中时返回 404",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.IPInfoResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_ip / reserved_address",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found (strict)",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "429": {
+                        "description": "rate_limited",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/network/{cidr}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "列出网段内 City/ASN/GeoCN 数据库中的所有子网及其记录, 按数据库、网段顺序分页返回 (需要 ` + "`" + `network` + "`" + ` 权限的密钥)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "网段查询"
+                ],
+                "summary": "网段查询",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CIDR, 如 203.0.113.0/22",
+                        "name": "cidr",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "逗号分隔的数据库: city,asn,cn (默认全部)",
+                        "name": "db",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "偏移量, 不超过 NETWORK_MAX_OFFSET",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数, 不超过 NETWORK_MAX_LIMIT",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.NetworkListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_network / invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "503": {
+                        "description": "db_unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyHeader": []
+                    },
+                    {
+                        "ApiKeyQuery": []
+                    },
+                    {
+                        "HMACSignature": []
+                    }
+                ],
+                "description": "返回当前密钥按月累计的请求数与计费单位(批量查询按 IP 数计)及今日配额使用情况; admin 密钥可通过 key_id 查询其他密钥 (需要任意有效密钥)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "密钥管理"
+                ],
+                "summary": "用量查询",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UTC 月份, 如 2026-10, 默认当月",
+                        "name": "month",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "要查询的密钥 ID, 仅 admin 可用",
+                        "name": "key_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/model.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/common.ResponseResult"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "common.ResponseResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_quota": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.APIKeyCreateRequest": {
+            "type": "object",
+            "properties": {
+                "daily_quota": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "type": "integer"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Successful response",
-                        "schema": {
-                            "$ref": "#/definitions/model.IPInfoResponse"
-                        }
+                }
+            }
+        },
+        "model.APIKeySecretResponse": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "$ref": "#/definitions/model.APIKey"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ASNInfo": {
+            "type": "object",
+            "properties": {
+                "asn": {
+                    "type": "integer"
+                },
+                "ipv4_addresses": {
+                    "type": "integer"
+                },
+                "ipv4_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ipv6_prefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "ipv6_slash64s": {
+                    "type": "integer"
+                },
+                "organization": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "model.ASNSearchResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "next_offset": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "org": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ASNSummary"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.ASNSummary": {
+            "type": "object",
+            "properties": {
+                "asn": {
+                    "type": "integer"
+                },
+                "ipv4_addresses": {
+                    "type": "integer"
+                },
+                "ipv6_slash64s": {
+                    "type": "integer"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "prefix_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.BatchRequest": {
+            "type": "object",
+            "required": [
+                "ips"
+            ],
+            "properties": {
+                "ips": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchResult"
+                    }
+                }
+            }
+        },
+        "model.BatchResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/model.IPInfoResponse"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Cloud": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "service": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CountryNetworksResponse": {
+            "type": "object",
+            "properties": {
+                "build_epoch": {
+                    "type": "integer"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "ipv4": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ipv6": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.DatabaseInfo": {
+            "type": "object",
+            "properties": {
+                "build_epoch": {
+                    "type": "integer"
+                },
+                "build_time": {
+                    "type": "string"
+                },
+                "database_type": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "file": {
+                    "type": "string"
+                },
+                "ip_version": {
+                    "type": "integer"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "loaded": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "node_count": {
+                    "type": "integer"
+                },
+                "record_size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DualStackResponse": {
+            "type": "object",
+            "properties": {
+                "ip": {
+                    "type": "string"
+                },
+                "ipv4_url": {
+                    "type": "string"
+                },
+                "ipv6_url": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Found": {
+            "type": "object",
+            "properties": {
+                "asn": {
+                    "type": "boolean"
+                },
+                "city": {
+                    "type": "boolean"
+                },
+                "cn": {
+                    "type": "boolean"
+                }
+            }
+        },
         "model.IPInfoResponse": {
             "type": "object",
             "properties": {
                 "addr": {
                     "type": "string"
                 },
-                "asn": {
+                "as": {
                     "type": "string"
                 },
+                "asn": {
+                    "type": "integer"
+                },
                 "city": {
                     "type": "string"
                 },
+                "cloud": {
+                    "$ref": "#/definitions/model.Cloud"
+                },
                 "country": {
                     "type": "string"
                 },
+                "country_code": {
+                    "type": "string"
+                },
                 "district": {
                     "type": "string"
                 },
+                "found": {
+                    "$ref": "#/definitions/model.Found"
+                },
                 "ip": {
                     "type": "string"
                 },
                 "latitude": {
-                    "type": "string"
+                    "type": "number"
                 },
                 "longitude": {
-                    "type": "string"
+                    "type": "number"
                 },
                 "province": {
                     "type": "string"
                 },
+                "rdns": {
+                    "$ref": "#/definitions/model.ReverseDNS"
+                },
                 "registered_country": {
                     "type": "string"
                 },
+                "security": {
+                    "$ref": "#/definitions/model.Security"
+                },
                 "subdivisions": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.NetworkEntry": {
+            "type": "object",
+            "properties": {
+                "database": {
+                    "type": "string"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "record": {}
+            }
+        },
+        "model.NetworkListResponse": {
+            "type": "object",
+            "properties": {
+                "databases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "networks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NetworkEntry"
+                    }
+                },
+                "next_offset": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.PTRRecord": {
+            "type": "object",
+            "properties": {
+                "forward_confirmed": {
+                    "type": "boolean"
+                },
+                "hostname": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ReverseDNS": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "hostnames": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PTRRecord"
+                    }
+                }
+            }
+        },
+        "model.Security": {
+            "type": "object",
+            "properties": {
+                "is_anonymous": {
+                    "type": "boolean"
+                },
+                "is_hosting": {
+                    "type": "boolean"
+                },
+                "is_proxy": {
+                    "type": "boolean"
+                },
+                "is_residential_proxy": {
+                    "type": "boolean"
+                },
+                "is_tor": {
+                    "type": "boolean"
+                },
+                "is_vpn": {
+                    "type": "boolean"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.ServiceInfo": {
+            "type": "object",
+            "properties": {
+                "databases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DatabaseInfo"
+                    }
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.UsageResponse": {
+            "type": "object",
+            "properties": {
+                "daily_quota": {
+                    "type": "integer"
+                },
+                "daily_used": {
+                    "type": "integer"
+                },
+                "key_id": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "routes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "units": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyHeader": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "ApiKeyQuery": {
+            "type": "apiKey",
+            "name": "key",
+            "in": "query"
+        },
+        "BearerAuth": {
+            "description": "Bearer \u003c密钥或 JWT\u003e",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "HMACSignature": {
+            "description": "HMAC-SHA256 签名, 同时需要 X-Signature-Key、X-Signature-Timestamp 与 X-Signature-Nonce, 见 README",
+            "type": "apiKey",
+            "name": "X-Signature",
+            "in": "header"
+        }
     }
 }`
 
@@ -96,8 +1563,8 @@ var SwaggerInfo = &swag.Spec{
 	Host:             "",
 	BasePath:         "",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "go-geoip",
+	Description:      "IP 地理位置查询服务. 创建过密钥或配置了 API_SECRET 后, 标注了 Security 的接口需要携带密钥, 三种方式任选其一",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-geoip/common"
+	logger "go-geoip/common/loggger"
+	"go-geoip/common/lru"
+	"go-geoip/controller"
+	"go-geoip/model"
+)
+
+// combinedLogPattern nginx 默认的 combined 格式, 与 apache combined 相同; 缺少 referer/agent 时即 common 格式
+const combinedLogPattern = `^(?P<ip>\S+) \S+ (?P<user>\S+) \[(?P<time>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<bytes>\S+)(?: "(?P<referer>[^"]*)" "(?P<agent>[^"]*)")?`
+
+var logFormats = map[string]string{
+	"nginx-combined":  combinedLogPattern,
+	"apache-combined": combinedLogPattern,
+	"apache-common":   combinedLogPattern,
+}
+
+var errNoMatch = errors.New("no_match")
+
+type lookupResult struct {
+	info *model.IPInfoResponse
+	err  error
+}
+
+type enrichLine struct {
+	number int
+	text   string
+	fields map[string]string
+	result lookupResult
+	done   chan struct{}
+}
+
+type enrichJSONLine struct {
+	Line   int                   `json:"line"`
+	Fields map[string]string     `json:"fields,omitempty"`
+	Geo    *model.IPInfoResponse `json:"geo,omitempty"`
+	Error  string                `json:"error,omitempty"`
+}
+
+// enrichCommand go-geoip enrich [--format nginx-combined|apache-combined|apache-common] [--regex <re>] [--output jsonl|append] [file...]
+func enrichCommand(args []string) {
+	fs := flag.NewFlagSet("enrich", flag.ExitOnError)
+	format := fs.String("format", "nginx-combined", "log format: nginx-combined, apache-combined or apache-common")
+	pattern := fs.String("regex", "", "custom log regex with a named (?P<ip>...) group, overrides --format")
+	output := fs.String("output", "jsonl", "output mode: jsonl, or append to add geo fields to the original line")
+	workers := fs.Int("workers", runtime.NumCPU(), "concurrent lookups")
+	cacheSize := fs.Int("cache-size", 10000, "LRU cache size for lookup results")
+	_ = fs.Parse(args)
+
+	re, err := compileLogPattern(*format, *pattern)
+	if err != nil {
+		logger.FatalLog(err.Error())
+	}
+	if *output != "jsonl" && *output != "append" {
+		logger.FatalLog(fmt.Sprintf("unknown output mode %q", *output))
+	}
+
+	ensureDatabases(cliOptions(true))
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enricher := &logEnricher{
+		re:      re,
+		ipIndex: re.SubexpIndex("ip"),
+		cache:   lru.New[string, lookupResult](*cacheSize),
+		workers: max(*workers, 1),
+		write:   newEnrichWriter(out, *output),
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, file := range files {
+		if err := enricher.enrichFile(file); err != nil {
+			out.Flush()
+			logger.FatalLog(fmt.Sprintf("Failed to enrich %s: %v", file, err))
+		}
+	}
+}
+
+func compileLogPattern(format, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		var ok bool
+		if pattern, ok = logFormats[format]; !ok {
+			return nil, fmt.Errorf("unknown log format %q", format)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %v", err)
+	}
+	if re.SubexpIndex("ip") < 0 {
+		return nil, fmt.Errorf("regex must contain a named (?P<ip>...) group")
+	}
+	return re, nil
+}
+
+type logEnricher struct {
+	re      *regexp.Regexp
+	ipIndex int
+	cache   *lru.Cache[string, lookupResult]
+	workers int
+	write   func(*enrichLine) error
+}
+
+// enrichFile 并发查询, 按原始顺序输出: 读取协程同时把每行放入 jobs 与有序队列, 输出端按队列顺序等待各行完成
+func (e *logEnricher) enrichFile(file string) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	jobs := make(chan *enrichLine, e.workers*4)
+	ordered := make(chan *enrichLine, e.workers*64)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range jobs {
+				e.lookup(line)
+				close(line.done)
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for number := 1; scanner.Scan(); number++ {
+			line := &enrichLine{number: number, text: scanner.Text(), done: make(chan struct{})}
+			ordered <- line
+			jobs <- line
+		}
+		readErr = scanner.Err()
+	}()
+
+	var writeErr error
+	for line := range ordered {
+		<-line.done
+		if writeErr == nil {
+			writeErr = e.write(line)
+		}
+	}
+	wg.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	return writeErr
+}
+
+func (e *logEnricher) lookup(line *enrichLine) {
+	match := e.re.FindStringSubmatch(line.text)
+	if match == nil {
+		line.result.err = errNoMatch
+		return
+	}
+	line.fields = make(map[string]string)
+	for i, name := range e.re.SubexpNames() {
+		if name != "" && match[i] != "" {
+			line.fields[name] = match[i]
+		}
+	}
+
+	ip := match[e.ipIndex]
+	if result, ok := e.cache.Get(ip); ok {
+		line.result = result
+		return
+	}
+	info, err := controller.LookupIP(context.Background(), ip, controller.LookupOptions{})
+	line.result = lookupResult{info: info, err: err}
+	e.cache.Add(ip, line.result)
+}
+
+func newEnrichWriter(out io.Writer, mode string) func(*enrichLine) error {
+	if mode == "append" {
+		return func(line *enrichLine) error {
+			_, err := io.WriteString(out, line.text+appendGeoFields(line.result)+"\n")
+			return err
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return func(line *enrichLine) error {
+		result := enrichJSONLine{Line: line.number, Fields: line.fields, Geo: line.result.info}
+		if line.result.err != nil {
+			result.Error = enrichErrorKey(line.result.err)
+		}
+		return enc.Encode(result)
+	}
+}
+
+// appendGeoFields 追加到原始行末尾的 key="value" 字段, 查询失败时只追加 geo_error
+func appendGeoFields(result lookupResult) string {
+	if result.err != nil {
+		return " geo_error=" + strconv.Quote(enrichErrorKey(result.err))
+	}
+	info := result.info
+	fields := []string{
+		"geo_country_code=" + strconv.Quote(info.CountryCode),
+		"geo_country=" + strconv.Quote(info.Country),
+		"geo_province=" + strconv.Quote(info.Province),
+		"geo_city=" + strconv.Quote(info.City),
+		"geo_asn=" + strconv.FormatUint(uint64(info.ASN), 10),
+		"geo_as=" + strconv.Quote(info.AS),
+	}
+	return " " + strings.Join(fields, " ")
+}
+
+// enrichErrorKey 查询错误使用错误码目录中的 key, 行不匹配时为 no_match
+func enrichErrorKey(err error) string {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Key
+	}
+	return err.Error()
+}
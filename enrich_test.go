@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-geoip/common/lru"
+	"go-geoip/controller"
+	"go-geoip/geoip/geoiptest"
+)
+
+// runEnrich 将 lines 写入临时文件后按指定格式与输出方式补充, 返回输出的各行
+func runEnrich(t *testing.T, format, pattern, output string, workers int, lines []string) []string {
+	t.Helper()
+	controller.SetEngine(geoiptest.NewEngine(t))
+	re, err := compileLogPattern(format, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	enricher := &logEnricher{
+		re:      re,
+		ipIndex: re.SubexpIndex("ip"),
+		cache:   lru.New[string, lookupResult](0), // 不缓存, 每行都并发查询
+		workers: workers,
+		write:   newEnrichWriter(w, output),
+	}
+	if err := enricher.enrichFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func combinedLine(ip string, n int) string {
+	return fmt.Sprintf(`%s - - [19/Oct/2026:12:00:00 +0000] "GET /page/%d HTTP/1.1" 200 512 "-" "curl/8.0"`, ip, n)
+}
+
+// 多个 worker 并发查询时输出顺序与输入一致
+func TestEnrichOrder(t *testing.T) {
+	ips := []string{"8.8.8.8", "1.1.1.1", "1.0.1.1", "203.0.113.200", "2001:4860::1", "999.1.1.1"}
+	want := []string{"US", "AU", "CN", "JP", "US", ""}
+	var lines []string
+	for i := range 500 {
+		lines = append(lines, combinedLine(ips[i%len(ips)], i))
+	}
+
+	got := runEnrich(t, "nginx-combined", "", "jsonl", 16, lines)
+	if len(got) != len(lines) {
+		t.Fatalf("got %d lines, want %d", len(got), len(lines))
+	}
+	for i, text := range got {
+		var line enrichJSONLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		countryCode := ""
+		if line.Geo != nil {
+			countryCode = line.Geo.CountryCode
+		}
+		if line.Line != i+1 || line.Fields["request"] != fmt.Sprintf("GET /page/%d HTTP/1.1", i) || countryCode != want[i%len(ips)] {
+			t.Fatalf("output %d = %s", i+1, text)
+		}
+	}
+}
+
+func TestEnrichOutput(t *testing.T) {
+	lines := []string{
+		combinedLine("8.8.8.8", 1),
+		"not an access log line",
+		combinedLine("999.1.1.1", 2),
+		"",
+	}
+
+	tests := []struct {
+		output string
+		want   []string // 各行输出的前缀或完整内容
+	}{
+		{"jsonl", []string{
+			`{"line":1,"fields":{"agent":"curl/8.0","bytes":"512","ip":"8.8.8.8",`,
+			`{"line":2,"error":"no_match"}`,
+			`{"line":3,"fields":{"agent":"curl/8.0","bytes":"512","ip":"999.1.1.1",`,
+			`{"line":4,"error":"no_match"}`,
+		}},
+		{"append", []string{
+			lines[0] + ` geo_country_code="US" `,
+			lines[1] + ` geo_error="no_match"`,
+			lines[2] + ` geo_error="invalid_ip"`,
+			` geo_error="no_match"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			got := runEnrich(t, "nginx-combined", "", tt.output, 4, lines)
+			if len(got) != len(tt.want) {
+				t.Fatalf("output = %q", got)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("line %d = %s, want prefix %s", i+1, got[i], want)
+				}
+			}
+			if tt.output == "jsonl" {
+				if !strings.Contains(got[0], `"geo":{`) || !strings.Contains(got[2], `"error":"invalid_ip"`) || strings.Contains(got[2], `"geo"`) {
+					t.Errorf("output = %q", got)
+				}
+			} else if !strings.Contains(got[0], " geo_asn=15169 ") {
+				t.Errorf("line 1 = %s", got[0])
+			}
+		})
+	}
+}
+
+func TestEnrichCustomRegex(t *testing.T) {
+	for _, pattern := range []string{`^(\S+) (\S+)`, `^(?P<addr>\S+)`, `^(?P<ip>\S+`} {
+		if _, err := compileLogPattern("nginx-combined", pattern); err == nil {
+			t.Errorf("regex %q accepted", pattern)
+		}
+	}
+	if _, err := compileLogPattern("iis", ""); err == nil {
+		t.Error("unknown format accepted")
+	}
+
+	got := runEnrich(t, "", `^(?P<path>\S+) client=(?P<ip>\S+)`, "jsonl", 2, []string{
+		"/login client=1.0.1.1",
+		"/logout",
+	})
+	var line enrichJSONLine
+	if err := json.Unmarshal([]byte(got[0]), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Fields["path"] != "/login" || line.Fields["ip"] != "1.0.1.1" || line.Geo == nil || line.Geo.CountryCode != "CN" {
+		t.Errorf("line 1 = %s", got[0])
+	}
+	if got[1] != `{"line":2,"error":"no_match"}` {
+		t.Errorf("line 2 = %s", got[1])
+	}
+}
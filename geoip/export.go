@@ -0,0 +1,208 @@
+package geoip
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go-geoip/common/iptrie"
+	"go-geoip/model"
+)
+
+// ExportFormats 支持的导出格式
+var ExportFormats = []string{"csv", "jsonl"}
+
+var exportColumns = []string{
+	"network", "country", "country_code", "registered_country", "subdivisions",
+	"province", "city", "district", "latitude", "longitude", "asn", "as",
+}
+
+// 每写出多少行刷新一次, w 实现 http.Flusher 时客户端能持续收到数据
+const exportFlushRows = 1000
+
+// Export 遍历 City 数据库, 按 Lookup 的规则合并 ASN 与 GeoCN 数据后逐行写入 w, 不在内存中缓存完整数据集; ctx 取消时停止
+func (e *Engine) Export(ctx context.Context, w io.Writer, format string) error {
+	var write func(model.ExportRecord) error
+	var flush func() error
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		write = func(r model.ExportRecord) error { return cw.Write(csvRow(r)) }
+		flush = func() error { cw.Flush(); return cw.Error() }
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		write = func(r model.ExportRecord) error { return enc.Encode(r) }
+		flush = func() error { return nil }
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+
+	dbs, err := e.Acquire()
+	if err != nil {
+		return err
+	}
+	defer dbs.Release()
+
+	rows := 0
+	emit := func(r model.ExportRecord) error {
+		if err := write(r); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return nil
+	}
+
+	networks := dbs.City.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var city model.City
+		network, err := networks.Network(&city)
+		if err != nil {
+			return err
+		}
+		if err := e.exportCityNetwork(toPrefix(network), city, dbs, emit); err != nil {
+			return err
+		}
+	}
+	if err := networks.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// exportCityNetwork 按 ASN 与 GeoCN 的网段边界拆分 City 网段, 并按 Lookup 的顺序合并: City -> ASN -> GeoCN (仅中国)
+func (e *Engine) exportCityNetwork(prefix netip.Prefix, city model.City, dbs *Databases, emit func(model.ExportRecord) error) error {
+	base := model.IPInfoResponse{}
+	e.applyCityRecord(city, &base)
+
+	asnParts, err := subdivide[model.ASN](dbs.ASN, prefix)
+	if err != nil {
+		return err
+	}
+	for _, asnPart := range asnParts {
+		info := base
+		if asnPart.record != nil {
+			applyASNRecord(*asnPart.record, &info)
+		}
+		if info.CountryCode != "CN" {
+			if err := emit(newExportRecord(asnPart.prefix, &info)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cnParts, err := subdivide[model.GeoCN](dbs.CN, asnPart.prefix)
+		if err != nil {
+			return err
+		}
+		for _, cnPart := range cnParts {
+			cnInfo := info
+			if cnPart.record != nil {
+				applyCnRecord(*cnPart.record, &cnInfo)
+			}
+			if err := emit(newExportRecord(cnPart.prefix, &cnInfo)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type part[T any] struct {
+	prefix netip.Prefix
+	record *T
+}
+
+// subdivide 返回 prefix 被 reader 中网段切分后的各部分, 没有记录的空隙 record 为 nil
+func subdivide[T any](reader *maxminddb.Reader, prefix netip.Prefix) ([]part[T], error) {
+	if reader.Metadata.IPVersion == 4 && !prefix.Addr().Is4() {
+		return []part[T]{{prefix: prefix}}, nil
+	}
+
+	var parts []part[T]
+	cursor := prefix.Addr()
+	networks := reader.NetworksWithin(toIPNet(prefix), maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var record T
+		subnet, err := networks.Network(&record)
+		if err != nil {
+			return nil, err
+		}
+		sub := toPrefix(subnet)
+		// 整个 prefix 落在数据库的一个网段内
+		if sub.Bits() <= prefix.Bits() {
+			return []part[T]{{prefix: prefix, record: &record}}, nil
+		}
+		for _, gap := range iptrie.RangeToPrefixes(cursor, sub.Addr().Prev()) {
+			parts = append(parts, part[T]{prefix: gap})
+		}
+		parts = append(parts, part[T]{prefix: sub, record: &record})
+		cursor = iptrie.LastAddr(sub).Next()
+	}
+	if err := networks.Err(); err != nil {
+		return nil, err
+	}
+	for _, gap := range iptrie.RangeToPrefixes(cursor, iptrie.LastAddr(prefix)) {
+		parts = append(parts, part[T]{prefix: gap})
+	}
+	return parts, nil
+}
+
+func newExportRecord(prefix netip.Prefix, info *model.IPInfoResponse) model.ExportRecord {
+	subdivisions := info.Subdivisions
+	if subdivisions == nil {
+		subdivisions = []string{}
+	}
+	return model.ExportRecord{
+		Network:           prefix.String(),
+		Country:           info.Country,
+		CountryCode:       info.CountryCode,
+		RegisteredCountry: info.RegisteredCountry,
+		Subdivisions:      subdivisions,
+		Province:          info.Province,
+		City:              info.City,
+		District:          info.District,
+		Latitude:          info.Latitude,
+		Longitude:         info.Longitude,
+		ASN:               info.ASN,
+		AS:                info.AS,
+	}
+}
+
+func csvRow(r model.ExportRecord) []string {
+	asn := ""
+	if r.ASN != 0 {
+		asn = strconv.FormatUint(uint64(r.ASN), 10)
+	}
+	return []string{
+		r.Network, r.Country, r.CountryCode, r.RegisteredCountry, strings.Join(r.Subdivisions, "|"),
+		r.Province, r.City, r.District, formatCoordinate(r.Latitude), formatCoordinate(r.Longitude), asn, r.AS,
+	}
+}
+
+func formatCoordinate(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
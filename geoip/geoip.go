@@ -0,0 +1,344 @@
+// Package geoip 是与 HTTP 服务无关的查询引擎: 按显式配置打开 MMDB 数据库, 合并 City/ASN/GeoCN 等结果,
+// 可嵌入其他程序使用, gin 控制器只是它之上的一层薄封装.
+//
+//	engine, err := geoip.New(geoip.Options{CityDB: "GeoIP-City.mmdb", ASNDB: "Geo-ASN.mmdb", CNDB: "GeoCN.mmdb"})
+//	defer engine.Close()
+//	info, err := engine.Lookup(ctx, netip.MustParseAddr("8.8.8.8"))
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go-geoip/common/asnindex"
+	"go-geoip/common/rdns"
+	"go-geoip/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 引擎创建的 span 所属的 instrumentation scope
+const tracerName = "go-geoip/geoip"
+
+// 数据库名称, 用于 Networks 的 databases 参数及 Info 的返回值
+const (
+	DatabaseCity      = "city"
+	DatabaseASN       = "asn"
+	DatabaseCN        = "cn"
+	DatabaseAnonymous = "anonymous"
+)
+
+var (
+	ErrInvalidAddr     = errors.New("geoip: invalid IP address")
+	ErrReservedAddress = errors.New("geoip: reserved IP address")
+	ErrClosed          = errors.New("geoip: engine closed")
+	ErrUnknownDatabase = errors.New("geoip: unknown database")
+	ErrUnknownFormat   = errors.New("geoip: unknown export format")
+)
+
+// DefaultLocales 名称取值的语言优先级
+var DefaultLocales = []string{"zh-CN", "en"}
+
+// Options 引擎的全部配置, 不读取环境变量
+type Options struct {
+	CityDB      string // City 数据库路径, 必需
+	ASNDB       string // ASN 数据库路径, 必需
+	CNDB        string // GeoCN 数据库路径, 必需
+	AnonymousDB string // Anonymous-IP 数据库路径, 可选; 配置后结果中包含 security 字段
+
+	// Locales 按顺序取第一个存在的名称, 为空时使用 DefaultLocales
+	Locales []string
+	// Providers 在数据库查询之后依次补充结果
+	Providers []Provider
+	// Resolver WithRDNS 使用的反向解析器, 为空时首次使用才创建默认解析器
+	Resolver *rdns.Resolver
+	// ASNIndex 加载时构建 ASN 反向索引, 供 ASN/SearchASN 使用
+	ASNIndex bool
+	// Observer 接收查询、加锁与缓存事件, 用于监控; 为空时不上报
+	Observer Observer
+	// TracerProvider 为每次查询的各数据库、反向解析与 Provider 创建子 span, 为空时使用 otel 的全局 TracerProvider
+	TracerProvider trace.TracerProvider
+}
+
+// 查询结果, 用于 Observer.ObserveLookup
+const (
+	LookupFound    = "found"
+	LookupNotFound = "not_found"
+	LookupError    = "error"
+)
+
+// Observer 引擎内部事件的监控接口, 方法会被并发调用且不应阻塞
+type Observer interface {
+	// ObserveLookup 每个数据库的每次查询, result 为 LookupFound/LookupNotFound/LookupError
+	ObserveLookup(database, result string)
+	// ObserveAcquire 取得数据库引用前等待锁的时间, Reload 替换数据库时会短暂阻塞
+	ObserveAcquire(wait time.Duration)
+	// ObserveCache 引擎内缓存 (如国家网段聚合) 的命中情况
+	ObserveCache(cache string, hit bool)
+}
+
+// Provider MMDB 之外的数据源, 如安全列表、云厂商 IP 段
+type Provider interface {
+	Name() string
+	Enrich(ctx context.Context, addr netip.Addr, info *model.IPInfoResponse)
+}
+
+// Reloader 实现该接口的 Provider 在 New 与 Reload 时重新加载自身数据; ctx 取消时应停止下载
+type Reloader interface {
+	Reload(ctx context.Context)
+}
+
+// Databases 一次加载得到的全部数据库, 由 Acquire 取得, 使用完毕后必须 Release
+type Databases struct {
+	City, ASN, CN *maxminddb.Reader
+	Anonymous     *maxminddb.Reader // 未配置时为 nil
+	ASNIndex      *asnindex.Index   // 未启用 Options.ASNIndex 时为 nil
+
+	refs sync.WaitGroup
+}
+
+// Release 释放 Acquire 取得的引用, 被替换的数据库在所有引用释放后关闭
+func (dbs *Databases) Release() {
+	dbs.refs.Done()
+}
+
+func (dbs *Databases) close() {
+	for _, reader := range []*maxminddb.Reader{dbs.City, dbs.ASN, dbs.CN, dbs.Anonymous} {
+		if reader != nil {
+			reader.Close()
+		}
+	}
+}
+
+// Engine 并发安全, Reload 期间进行中的查询继续使用旧数据库
+type Engine struct {
+	opts     Options
+	resolver func() *rdns.Resolver
+	tracer   trace.Tracer
+
+	// mu 只保护 dbs 指针的读取与替换, 查询本身不持锁
+	mu     sync.RWMutex
+	dbs    *Databases
+	closed bool
+
+	reloadMu sync.Mutex
+	hooks    []func()
+
+	countries countryCache
+}
+
+// New 打开全部数据库并加载 Providers
+func New(opts Options) (*Engine, error) {
+	return NewContext(context.Background(), opts)
+}
+
+// NewContext 与 New 相同, ctx 传给 Providers 的首次加载, 用于取消下载与链路追踪
+func NewContext(ctx context.Context, opts Options) (*Engine, error) {
+	if opts.CityDB == "" || opts.ASNDB == "" || opts.CNDB == "" {
+		return nil, errors.New("geoip: CityDB, ASNDB and CNDB are required")
+	}
+	if len(opts.Locales) == 0 {
+		opts.Locales = DefaultLocales
+	}
+
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	e := &Engine{opts: opts, tracer: tp.Tracer(tracerName)}
+	e.resolver = sync.OnceValue(func() *rdns.Resolver {
+		if opts.Resolver != nil {
+			return opts.Resolver
+		}
+		return rdns.New("", rdns.DefaultTimeout, rdns.DefaultTTL, rdns.DefaultCacheSize)
+	})
+
+	dbs, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	e.dbs = dbs
+	e.reloadProviders(ctx)
+	return e, nil
+}
+
+// OnReload 注册在每次 Reload 成功后调用的函数
+func (e *Engine) OnReload(fn func()) {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	e.hooks = append(e.hooks, fn)
+}
+
+// Reload 从 Options 中的路径重新打开数据库并原子替换, 再以 ctx 重新加载 Providers; 打开失败时返回错误并继续使用旧数据库
+func (e *Engine) Reload(ctx context.Context) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+
+	dbs, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		dbs.close()
+		return ErrClosed
+	}
+	old := e.dbs
+	e.dbs = dbs
+	e.mu.Unlock()
+	e.countries.reset()
+
+	// 旧数据库可能仍被 Lookup 或 Export 使用, 等待其全部释放后再关闭
+	go func() {
+		old.refs.Wait()
+		old.close()
+	}()
+
+	e.reloadProviders(ctx)
+	for _, hook := range e.hooks {
+		hook()
+	}
+	return nil
+}
+
+// Close 等待进行中的查询完成后关闭数据库, 之后的调用返回 ErrClosed
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	dbs := e.dbs
+	e.dbs = nil
+	e.mu.Unlock()
+
+	dbs.refs.Wait()
+	dbs.close()
+	return nil
+}
+
+// Acquire 取得当前数据库的引用, 长时间遍历数据库时使用
+func (e *Engine) Acquire() (*Databases, error) {
+	start := time.Now()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.opts.Observer != nil {
+		e.opts.Observer.ObserveAcquire(time.Since(start))
+	}
+	if e.closed {
+		return nil, ErrClosed
+	}
+	e.dbs.refs.Add(1)
+	return e.dbs, nil
+}
+
+// Info 返回已加载数据库的元数据, Anonymous-IP 数据库未配置时 Loaded 为 false
+func (e *Engine) Info() []model.DatabaseInfo {
+	files := []struct{ name, file string }{
+		{DatabaseCity, e.opts.CityDB},
+		{DatabaseASN, e.opts.ASNDB},
+		{DatabaseCN, e.opts.CNDB},
+		{DatabaseAnonymous, e.opts.AnonymousDB},
+	}
+	dbs, err := e.Acquire()
+	if err != nil {
+		infos := make([]model.DatabaseInfo, len(files))
+		for i, f := range files {
+			infos[i] = model.DatabaseInfo{Name: f.name, File: f.file}
+		}
+		return infos
+	}
+	defer dbs.Release()
+
+	readers := []*maxminddb.Reader{dbs.City, dbs.ASN, dbs.CN, dbs.Anonymous}
+	infos := make([]model.DatabaseInfo, len(files))
+	for i, f := range files {
+		if readers[i] == nil {
+			infos[i] = model.DatabaseInfo{Name: f.name, File: f.file}
+			continue
+		}
+		infos[i] = NewDatabaseInfo(f.name, f.file, readers[i])
+	}
+	return infos
+}
+
+// open 打开 Options 中的全部数据库, 任一失败时关闭已打开的部分
+func (e *Engine) open() (*Databases, error) {
+	dbs := &Databases{}
+	files := []struct {
+		name, file string
+		reader     **maxminddb.Reader
+	}{
+		{DatabaseCity, e.opts.CityDB, &dbs.City},
+		{DatabaseASN, e.opts.ASNDB, &dbs.ASN},
+		{DatabaseCN, e.opts.CNDB, &dbs.CN},
+		{DatabaseAnonymous, e.opts.AnonymousDB, &dbs.Anonymous},
+	}
+	for _, f := range files {
+		if f.file == "" {
+			continue
+		}
+		reader, err := maxminddb.Open(f.file)
+		if err != nil {
+			dbs.close()
+			return nil, fmt.Errorf("geoip: open %s database: %w", f.name, err)
+		}
+		*f.reader = reader
+	}
+
+	if e.opts.ASNIndex {
+		index, err := asnindex.Build(dbs.ASN)
+		if err != nil {
+			dbs.close()
+			return nil, fmt.Errorf("geoip: build ASN index: %w", err)
+		}
+		dbs.ASNIndex = index
+	}
+	return dbs, nil
+}
+
+func (e *Engine) reloadProviders(ctx context.Context) {
+	for _, provider := range e.opts.Providers {
+		if reloader, ok := provider.(Reloader); ok {
+			pctx, span := e.tracer.Start(ctx, "geoip.reload "+provider.Name())
+			reloader.Reload(pctx)
+			span.End()
+		}
+	}
+}
+
+// NewDatabaseInfo 从 Reader 的元数据构造 DatabaseInfo
+func NewDatabaseInfo(name, file string, reader *maxminddb.Reader) model.DatabaseInfo {
+	metadata := reader.Metadata
+	return model.DatabaseInfo{
+		Name:         name,
+		File:         file,
+		Loaded:       true,
+		DatabaseType: metadata.DatabaseType,
+		BuildEpoch:   metadata.BuildEpoch,
+		BuildTime:    time.Unix(int64(metadata.BuildEpoch), 0).UTC().Format(time.RFC3339),
+		IPVersion:    metadata.IPVersion,
+		NodeCount:    metadata.NodeCount,
+		RecordSize:   metadata.RecordSize,
+		Languages:    metadata.Languages,
+		Description:  metadata.Description,
+	}
+}
+
+// ReadDatabaseInfo 打开单个数据库文件读取元数据, 无需创建 Engine
+func ReadDatabaseInfo(name, file string) model.DatabaseInfo {
+	reader, err := maxminddb.Open(file)
+	if err != nil {
+		return model.DatabaseInfo{Name: name, File: file, Error: err.Error()}
+	}
+	defer reader.Close()
+	return NewDatabaseInfo(name, file, reader)
+}
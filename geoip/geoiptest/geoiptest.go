@@ -0,0 +1,140 @@
+// Package geoiptest 为测试生成小型的 City/ASN/GeoCN/Anonymous-IP 数据库, 用法类似 net/http/httptest.
+//
+//	engine, err := geoip.New(geoiptest.Options(t))
+package geoiptest
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"go-geoip/geoip"
+)
+
+// 测试数据库中的记录:
+//
+//	8.8.8.0/24        US Mountain View, AS15169 GOOGLE
+//	1.1.1.0/24        AU Sydney, AS13335 CLOUDFLARENET
+//	1.0.1.0/24        CN 福州, GeoCN 福建省/福州市/鼓楼区 电信
+//	203.0.113.0/25    JP Tokyo    } AS64500 EXAMPLE-NET, 同一国家的两个相邻网段
+//	203.0.113.128/25  JP Osaka    }
+//	2001:4860::/32    US Mountain View, AS15169 GOOGLE
+//	9.9.9.0/24        仅有注册国家 CH, Anonymous-IP 中标记为 VPN 与托管
+var (
+	cityRecords = map[string]mmdbtype.Map{
+		"8.8.8.0/24":       city("US", "美国", "United States", 37.751, -97.822, "California", "", "Mountain View"),
+		"1.1.1.0/24":       city("AU", "澳大利亚", "Australia", -33.494, 143.2104, "NSW", "", "Sydney"),
+		"1.0.1.0/24":       city("CN", "中国", "China", 26.06, 119.3, "Fujian", "福州", "Fuzhou"),
+		"203.0.113.0/25":   city("JP", "日本", "Japan", 35.6, 139.7, "Tokyo", "东京", "Tokyo"),
+		"203.0.113.128/25": city("JP", "日本", "Japan", 34.6, 135.5, "Osaka", "大阪", "Osaka"),
+		"2001:4860::/32":   city("US", "美国", "United States", 37.751, -97.822, "California", "", "Mountain View"),
+		"9.9.9.0/24": {
+			"registered_country": mmdbtype.Map{"iso_code": mmdbtype.String("CH"), "names": names("瑞士", "Switzerland")},
+		},
+	}
+	asnRecords = map[string]mmdbtype.Map{
+		"8.8.8.0/24":     asn(15169, "GOOGLE"),
+		"2001:4860::/32": asn(15169, "GOOGLE"),
+		"1.1.1.0/24":     asn(13335, "CLOUDFLARENET"),
+		"203.0.113.0/24": asn(64500, "EXAMPLE-NET"),
+	}
+	cnRecords = map[string]mmdbtype.Map{
+		"1.0.1.0/24": {
+			"province":  mmdbtype.String("福建省"),
+			"city":      mmdbtype.String("福州市"),
+			"districts": mmdbtype.String("鼓楼区"),
+			"isp":       mmdbtype.String("电信"),
+			"net":       mmdbtype.String("宽带"),
+		},
+	}
+	anonymousRecords = map[string]mmdbtype.Map{
+		"9.9.9.0/24": {
+			"is_anonymous":        mmdbtype.Bool(true),
+			"is_anonymous_vpn":    mmdbtype.Bool(true),
+			"is_hosting_provider": mmdbtype.Bool(true),
+		},
+	}
+)
+
+// Options 在 t.TempDir() 中写入全部测试数据库, 返回指向它们的引擎配置
+func Options(t testing.TB) geoip.Options {
+	t.Helper()
+	dir := t.TempDir()
+	opts := geoip.Options{
+		CityDB:      filepath.Join(dir, "GeoIP-City.mmdb"),
+		ASNDB:       filepath.Join(dir, "Geo-ASN.mmdb"),
+		CNDB:        filepath.Join(dir, "GeoCN.mmdb"),
+		AnonymousDB: filepath.Join(dir, "GeoIP-Anonymous.mmdb"),
+	}
+	write(t, opts.CityDB, "GeoLite2-City", 6, cityRecords)
+	write(t, opts.ASNDB, "GeoLite2-ASN", 6, asnRecords)
+	// 与线上的 GeoCN 一样只包含 IPv4
+	write(t, opts.CNDB, "GeoCN", 4, cnRecords)
+	write(t, opts.AnonymousDB, "GeoIP2-Anonymous-IP", 6, anonymousRecords)
+	return opts
+}
+
+// NewEngine 使用 Options 的数据库创建引擎, 测试结束时关闭
+func NewEngine(t testing.TB) *geoip.Engine {
+	t.Helper()
+	engine, err := geoip.New(Options(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func write(t testing.TB, path, databaseType string, ipVersion int, records map[string]mmdbtype.Map) {
+	t.Helper()
+	w, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: databaseType, IPVersion: ipVersion, IncludeReservedNetworks: true, RecordSize: 28})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Insert(network, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := w.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(zh, en string) mmdbtype.Map {
+	m := mmdbtype.Map{"en": mmdbtype.String(en)}
+	if zh != "" {
+		m["zh-CN"] = mmdbtype.String(zh)
+	}
+	return m
+}
+
+func city(iso, zh, en string, lat, lon float64, subdivision, cityZh, cityEn string) mmdbtype.Map {
+	country := mmdbtype.Map{"iso_code": mmdbtype.String(iso), "names": names(zh, en)}
+	return mmdbtype.Map{
+		"country":            country,
+		"registered_country": country,
+		"subdivisions":       mmdbtype.Slice{mmdbtype.Map{"names": names("", subdivision)}},
+		"city":               mmdbtype.Map{"names": names(cityZh, cityEn)},
+		"location":           mmdbtype.Map{"latitude": mmdbtype.Float64(lat), "longitude": mmdbtype.Float64(lon)},
+	}
+}
+
+func asn(number uint32, organization string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(number),
+		"autonomous_system_organization": mmdbtype.String(organization),
+	}
+}
@@ -0,0 +1,260 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go-geoip/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SourceAnonymousDB Security.Sources 中表示命中 Anonymous-IP 数据库的来源名称
+const SourceAnonymousDB = "anonymous_db"
+
+type lookupOptions struct {
+	rdns bool
+}
+
+// LookupOption 控制 Lookup 的可选增强项
+type LookupOption func(*lookupOptions)
+
+// WithRDNS 同时返回 PTR 反向解析结果, 与数据库查询并发进行
+func WithRDNS() LookupOption {
+	return func(o *lookupOptions) { o.rdns = true }
+}
+
+// Lookup 依次查询 ASN、City、GeoCN (仅中国) 与 Anonymous-IP 数据库, 再由 Providers 补充结果
+func (e *Engine) Lookup(ctx context.Context, addr netip.Addr, opts ...LookupOption) (*model.IPInfoResponse, error) {
+	var o lookupOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if !addr.IsValid() {
+		return nil, ErrInvalidAddr
+	}
+	addr = addr.Unmap().WithZone("")
+	if isReserved(addr) {
+		return nil, fmt.Errorf("%w: %s", ErrReservedAddress, addr)
+	}
+
+	ctx, span := e.tracer.Start(ctx, "geoip.Lookup", trace.WithAttributes(attribute.String("geoip.addr", addr.String())))
+	defer span.End()
+
+	info := &model.IPInfoResponse{IP: addr.String(), Version: Version(addr)}
+
+	// 反向解析与 MMDB 查询并发进行, rdns.Lookup 自身保证不超过配置的超时
+	var rdnsResult chan *model.ReverseDNS
+	if o.rdns {
+		rdnsResult = make(chan *model.ReverseDNS, 1)
+		go func() {
+			rdnsResult <- e.reverseLookup(ctx, addr)
+		}()
+	}
+
+	dbs, err := e.Acquire()
+	if err != nil {
+		setSpanError(span, err)
+		return nil, err
+	}
+	err = e.lookupDatabases(ctx, dbs, addr, info)
+	dbs.Release()
+	if err != nil {
+		setSpanError(span, err)
+		return nil, err
+	}
+
+	for _, provider := range e.opts.Providers {
+		pctx, span := e.tracer.Start(ctx, "geoip.enrich "+provider.Name())
+		provider.Enrich(pctx, addr, info)
+		span.End()
+	}
+
+	if rdnsResult != nil {
+		info.RDNS = <-rdnsResult
+	}
+	return info, nil
+}
+
+func (e *Engine) lookupDatabases(ctx context.Context, dbs *Databases, addr netip.Addr, info *model.IPInfoResponse) error {
+	ip := net.IP(addr.AsSlice())
+
+	var asn model.ASN
+	_, ok, err := e.lookupNetwork(ctx, DatabaseASN, dbs.ASN, ip, &asn)
+	if err != nil {
+		return err
+	}
+	info.Found.ASN = ok
+	applyASNRecord(asn, info)
+
+	var city model.City
+	network, ok, err := e.lookupNetwork(ctx, DatabaseCity, dbs.City, ip, &city)
+	if err != nil {
+		return err
+	}
+	if ok {
+		info.Found.City = true
+		info.Addr = network.String()
+		e.applyCityRecord(city, info)
+	}
+
+	if info.CountryCode == "CN" {
+		var geoCN model.GeoCN
+		network, ok, err := e.lookupNetwork(ctx, DatabaseCN, dbs.CN, ip, &geoCN)
+		if err == nil && ok {
+			info.Found.CN = true
+			info.Addr = network.String()
+			applyCnRecord(geoCN, info)
+		}
+	}
+
+	if dbs.Anonymous != nil {
+		var anon model.AnonymousIP
+		_, _, err := e.lookupNetwork(ctx, DatabaseAnonymous, dbs.Anonymous, ip, &anon)
+		if err != nil {
+			return err
+		}
+		info.Security = newSecurity(anon)
+	}
+	return nil
+}
+
+// lookupNetwork 在子 span 中查询一个数据库, 并上报 Observer
+func (e *Engine) lookupNetwork(ctx context.Context, database string, db *maxminddb.Reader, ip net.IP, result any) (*net.IPNet, bool, error) {
+	_, span := e.tracer.Start(ctx, "geoip.lookup "+database, trace.WithAttributes(attribute.String("geoip.database", database)))
+	defer span.End()
+
+	network, ok, err := db.LookupNetwork(ip, result)
+	e.observeLookup(database, ok, err)
+	span.SetAttributes(attribute.Bool("geoip.found", ok))
+	if err != nil {
+		setSpanError(span, err)
+	}
+	return network, ok, err
+}
+
+// reverseLookup 反向解析的 span 记录返回的主机名数量, 超时或失败时记录错误信息
+func (e *Engine) reverseLookup(ctx context.Context, addr netip.Addr) *model.ReverseDNS {
+	ctx, span := e.tracer.Start(ctx, "rdns.Lookup")
+	defer span.End()
+
+	result := e.resolver().Lookup(ctx, addr.String())
+	if result != nil {
+		span.SetAttributes(attribute.Int("rdns.hostnames", len(result.Hostnames)))
+		if result.Error != "" {
+			span.SetStatus(codes.Error, result.Error)
+		}
+	}
+	return result
+}
+
+func setSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func (e *Engine) observeLookup(database string, found bool, err error) {
+	if e.opts.Observer == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		e.opts.Observer.ObserveLookup(database, LookupError)
+	case found:
+		e.opts.Observer.ObserveLookup(database, LookupFound)
+	default:
+		e.opts.Observer.ObserveLookup(database, LookupNotFound)
+	}
+}
+
+// isReserved 私有、回环、链路本地、组播及未指定地址不在任何数据库中, 直接拒绝
+func isReserved(addr netip.Addr) bool {
+	return addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast()
+}
+
+func applyASNRecord(asn model.ASN, info *model.IPInfoResponse) {
+	info.ASN = asn.Number
+	info.AS = asn.Organization
+}
+
+func (e *Engine) applyCityRecord(city model.City, info *model.IPInfoResponse) {
+	info.Country = e.countryName(city.Country.Names)
+	info.CountryCode = city.Country.ISOCode
+	info.RegisteredCountry = e.countryName(city.RegisteredCountry.Names)
+	info.Latitude = city.Location.Latitude
+	info.Longitude = city.Location.Longitude
+	info.Subdivisions = e.subdivisionNames(city.Subdivisions)
+	info.City, _ = e.localName(city.City.Names)
+}
+
+// applyCnRecord GeoCN 的省市区与运营商覆盖 City/ASN 数据库的结果, 直辖市以省级名称作为城市
+func applyCnRecord(geoCN model.GeoCN, info *model.IPInfoResponse) {
+	if strings.HasSuffix(geoCN.Province, "市") {
+		info.Province = geoCN.Province
+		info.City = geoCN.Province
+		info.District = geoCN.City
+	} else {
+		info.Province = geoCN.Province
+		info.City = geoCN.City
+		info.District = geoCN.Districts
+	}
+
+	info.AS = geoCN.ISP
+	if geoCN.Net != "" {
+		info.AS += " (" + geoCN.Net + ")"
+	}
+}
+
+func newSecurity(anon model.AnonymousIP) *model.Security {
+	result := &model.Security{
+		IsAnonymous:        anon.IsAnonymous,
+		IsVPN:              anon.IsAnonymousVPN,
+		IsTor:              anon.IsTorExitNode,
+		IsProxy:            anon.IsPublicProxy,
+		IsResidentialProxy: anon.IsResidentialProxy,
+		IsHosting:          anon.IsHostingProvider,
+		Sources:            []string{},
+	}
+	if anon != (model.AnonymousIP{}) {
+		result.Sources = append(result.Sources, SourceAnonymousDB)
+	}
+	return result
+}
+
+// localName 按 Locales 顺序返回第一个存在的名称及其语言
+func (e *Engine) localName(names map[string]string) (string, string) {
+	for _, locale := range e.opts.Locales {
+		if name, ok := names[locale]; ok {
+			return name, locale
+		}
+	}
+	return "", ""
+}
+
+func (e *Engine) countryName(names map[string]string) string {
+	name, locale := e.localName(names)
+	if locale == "zh-CN" {
+		switch name {
+		case "香港", "澳门", "台湾":
+			return "中国" + name
+		}
+	}
+	return name
+}
+
+func (e *Engine) subdivisionNames(subdivisions []model.Subdivision) []string {
+	var names []string
+	for _, subdivision := range subdivisions {
+		name, _ := e.localName(subdivision.Names)
+		names = append(names, name)
+	}
+	return names
+}
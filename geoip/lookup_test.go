@@ -0,0 +1,130 @@
+package geoip_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"go-geoip/geoip"
+	"go-geoip/geoip/geoiptest"
+	"go-geoip/model"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanProvider 在 Enrich 传入的 ctx 下创建子 span, 用于检查 span 的父子关系
+type spanProvider struct {
+	tracer trace.Tracer
+}
+
+func (p spanProvider) Name() string { return "test" }
+
+func (p spanProvider) Enrich(ctx context.Context, addr netip.Addr, info *model.IPInfoResponse) {
+	_, span := p.tracer.Start(ctx, "test.enrich")
+	span.End()
+}
+
+func TestLookupSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := geoiptest.Options(t)
+	opts.TracerProvider = tp
+	opts.Providers = []geoip.Provider{spanProvider{tp.Tracer("test")}}
+	engine, err := geoip.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if _, err := engine.Lookup(context.Background(), netip.MustParseAddr("8.8.8.8")); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["geoip.Lookup"]
+	if !ok {
+		t.Fatalf("no geoip.Lookup span in %v", spanNames(recorder.Ended()))
+	}
+	for _, name := range []string{"geoip.lookup asn", "geoip.lookup city", "geoip.lookup anonymous", "geoip.enrich test"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("no %s span in %v", name, spanNames(recorder.Ended()))
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of geoip.Lookup", name)
+		}
+	}
+
+	// Provider 自己的 span 挂在 geoip.enrich 之下
+	enrich, child := spans["geoip.enrich test"], spans["test.enrich"]
+	if child == nil || enrich == nil || child.Parent().SpanID() != enrich.SpanContext().SpanID() {
+		t.Errorf("test.enrich is not a child of geoip.enrich test")
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	return names
+}
+
+// reloadProvider 记录 Reload 收到的 ctx
+type reloadProvider struct {
+	contexts []context.Context
+}
+
+func (p *reloadProvider) Name() string { return "reload" }
+
+func (p *reloadProvider) Enrich(ctx context.Context, addr netip.Addr, info *model.IPInfoResponse) {}
+
+func (p *reloadProvider) Reload(ctx context.Context) { p.contexts = append(p.contexts, ctx) }
+
+// Reload 的 ctx 传到 Provider: 取消信号可以中止下载, 加载过程的 span 挂在调用方的链路下
+func TestReloadContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	provider := &reloadProvider{}
+	opts := geoiptest.Options(t)
+	opts.TracerProvider = tp
+	opts.Providers = []geoip.Provider{provider}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "startup")
+	engine, err := geoip.NewContext(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	parent.End()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := engine.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(provider.contexts) != 2 {
+		t.Fatalf("Reload called %d times, want 2", len(provider.contexts))
+	}
+	if span := trace.SpanFromContext(provider.contexts[0]); span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("initial load is not part of the caller's trace")
+	}
+	if provider.contexts[1].Err() == nil {
+		t.Error("cancellation did not reach the provider")
+	}
+	var reloads int
+	for _, span := range recorder.Ended() {
+		if span.Name() == "geoip.reload reload" {
+			reloads++
+		}
+	}
+	if reloads != 2 {
+		t.Errorf("recorded %d geoip.reload spans, want 2", reloads)
+	}
+}
@@ -0,0 +1,265 @@
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go-geoip/common/asnindex"
+	"go-geoip/common/iptrie"
+	"go-geoip/model"
+)
+
+// NetworkDatabases Networks 可遍历的数据库
+var NetworkDatabases = []string{DatabaseCity, DatabaseASN, DatabaseCN}
+
+// Networks 列出 prefix 内各数据库中的子网及其记录, 按数据库、网段顺序分页.
+// 每次调用都从头跳过 offset 个网段, 调用方应限制 offset 的大小
+func (e *Engine) Networks(prefix netip.Prefix, databases []string, offset, limit int) (*model.NetworkListResponse, error) {
+	prefix = prefix.Masked()
+	result := &model.NetworkListResponse{
+		Network:   prefix.String(),
+		Databases: databases,
+		Offset:    offset,
+		Limit:     limit,
+		Networks:  []model.NetworkEntry{},
+	}
+
+	dbs, err := e.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer dbs.Release()
+
+	readers := map[string]*maxminddb.Reader{
+		DatabaseCity: dbs.City,
+		DatabaseASN:  dbs.ASN,
+		DatabaseCN:   dbs.CN,
+	}
+	network := toIPNet(prefix)
+
+	// 依次遍历各数据库, 跳过前 offset 条, 多读一条用于判断是否还有下一页
+	skipped := 0
+	for _, db := range databases {
+		reader, ok := readers[db]
+		if !ok {
+			return nil, ErrUnknownDatabase
+		}
+		// GeoCN 仅包含 IPv4, 其中没有 IPv6 网段 (NetworksWithin 会返回错误)
+		if reader.Metadata.IPVersion == 4 && !prefix.Addr().Is4() {
+			continue
+		}
+		networks := reader.NetworksWithin(network, maxminddb.SkipAliasedNetworks)
+		for networks.Next() {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if len(result.Networks) == limit {
+				next := offset + limit
+				result.NextOffset = &next
+				return result, nil
+			}
+			entry, err := e.decodeNetworkEntry(db, networks)
+			if err != nil {
+				return nil, err
+			}
+			result.Networks = append(result.Networks, entry)
+		}
+		if err := networks.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (e *Engine) decodeNetworkEntry(db string, networks *maxminddb.Networks) (model.NetworkEntry, error) {
+	entry := model.NetworkEntry{Database: db}
+	var (
+		subnet *net.IPNet
+		err    error
+	)
+	switch db {
+	case DatabaseCity:
+		var city model.City
+		if subnet, err = networks.Network(&city); err == nil {
+			entry.Record = e.newCityRecord(city)
+		}
+	case DatabaseASN:
+		var asn model.ASN
+		if subnet, err = networks.Network(&asn); err == nil {
+			entry.Record = model.ASNRecord{Number: asn.Number, Organization: asn.Organization}
+		}
+	case DatabaseCN:
+		var geoCN model.GeoCN
+		if subnet, err = networks.Network(&geoCN); err == nil {
+			entry.Record = model.CNRecord{
+				Province: geoCN.Province,
+				City:     geoCN.City,
+				District: geoCN.Districts,
+				ISP:      geoCN.ISP,
+				Net:      geoCN.Net,
+			}
+		}
+	}
+	if err != nil {
+		return entry, err
+	}
+	entry.Network = subnet.String()
+	return entry, nil
+}
+
+func (e *Engine) newCityRecord(city model.City) model.CityRecord {
+	var info model.IPInfoResponse
+	e.applyCityRecord(city, &info)
+	return model.CityRecord{
+		Country:           info.Country,
+		CountryCode:       info.CountryCode,
+		RegisteredCountry: info.RegisteredCountry,
+		Subdivisions:      info.Subdivisions,
+		City:              info.City,
+		Latitude:          info.Latitude,
+		Longitude:         info.Longitude,
+	}
+}
+
+// ASNIndex 返回当前的 ASN 反向索引, 未启用 Options.ASNIndex 时为 nil
+func (e *Engine) ASNIndex() (*asnindex.Index, error) {
+	dbs, err := e.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	// 索引构建完成后只读, 不依赖 Reader, 可在释放引用后继续使用
+	defer dbs.Release()
+	return dbs.ASNIndex, nil
+}
+
+// countryCache 按国家缓存聚合结果, Reload 时整体失效
+type countryCache struct {
+	sync.Mutex
+	generation int
+	networks   map[string]*model.CountryNetworksResponse
+	// building 正在遍历的国家, 同一代内对同一国家的并发请求共享一次遍历
+	building map[string]*countryBuild
+}
+
+type countryBuild struct {
+	done   chan struct{}
+	result *model.CountryNetworksResponse
+	err    error
+}
+
+func (c *countryCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	c.networks = nil
+	c.building = nil
+}
+
+// CountryNetworks 遍历 City 数据库, 返回国家 (ISO 3166-1 代码) 聚合后的 CIDR 列表
+func (e *Engine) CountryNetworks(iso string) (*model.CountryNetworksResponse, error) {
+	e.countries.Lock()
+	generation := e.countries.generation
+	cached, ok := e.countries.networks[iso]
+	build, building := e.countries.building[iso]
+	if !ok && !building {
+		build = &countryBuild{done: make(chan struct{})}
+		if e.countries.building == nil {
+			e.countries.building = map[string]*countryBuild{}
+		}
+		e.countries.building[iso] = build
+	}
+	e.countries.Unlock()
+	if e.opts.Observer != nil {
+		e.opts.Observer.ObserveCache("country_networks", ok)
+	}
+	if ok {
+		return cached, nil
+	}
+	if building {
+		<-build.done
+		return build.result, build.err
+	}
+
+	build.result, build.err = e.collectCountryNetworks(iso)
+	e.countries.Lock()
+	// 遍历整个 City 数据库耗时较长, 期间的 Reload 会使本次结果不进入缓存
+	if e.countries.generation == generation {
+		if build.err == nil {
+			if e.countries.networks == nil {
+				e.countries.networks = map[string]*model.CountryNetworksResponse{}
+			}
+			e.countries.networks[iso] = build.result
+		}
+		delete(e.countries.building, iso)
+	}
+	e.countries.Unlock()
+	close(build.done)
+	return build.result, build.err
+}
+
+func (e *Engine) collectCountryNetworks(iso string) (*model.CountryNetworksResponse, error) {
+	dbs, err := e.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer dbs.Release()
+	return collectCountryNetworks(dbs.City, iso)
+}
+
+func collectCountryNetworks(reader *maxminddb.Reader, iso string) (*model.CountryNetworksResponse, error) {
+	var ipv4, ipv6 []netip.Prefix
+
+	networks := reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var record struct {
+			Country struct {
+				ISOCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		network, err := networks.Network(&record)
+		if err != nil {
+			return nil, err
+		}
+		if record.Country.ISOCode != iso {
+			continue
+		}
+		prefix := toPrefix(network)
+		if prefix.Addr().Is4() {
+			ipv4 = append(ipv4, prefix)
+		} else {
+			ipv6 = append(ipv6, prefix)
+		}
+	}
+	if err := networks.Err(); err != nil {
+		return nil, err
+	}
+
+	return &model.CountryNetworksResponse{
+		Country:    iso,
+		BuildEpoch: reader.Metadata.BuildEpoch,
+		IPv4:       prefixStrings(iptrie.Collapse(ipv4)),
+		IPv6:       prefixStrings(iptrie.Collapse(ipv6)),
+	}, nil
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	result := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		result[i] = p.String()
+	}
+	return result
+}
+
+func toPrefix(network *net.IPNet) netip.Prefix {
+	addr, _ := netip.AddrFromSlice(network.IP)
+	bits, _ := network.Mask.Size()
+	return netip.PrefixFrom(addr.Unmap(), bits)
+}
+
+func toIPNet(prefix netip.Prefix) *net.IPNet {
+	addr := prefix.Addr()
+	return &net.IPNet{IP: addr.AsSlice(), Mask: net.CIDRMask(prefix.Bits(), addr.BitLen())}
+}
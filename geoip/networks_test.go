@@ -0,0 +1,136 @@
+package geoip_test
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-geoip/geoip"
+	"go-geoip/geoip/geoiptest"
+)
+
+// acquireCounter 统计 Acquire 次数: 缓存未命中时每次遍历 City 数据库前 Acquire 一次.
+// 测试数据库很小, 每次 Acquire 等待一段时间, 使并发请求在遍历完成前到达
+type acquireCounter struct {
+	acquires atomic.Int64
+}
+
+func (o *acquireCounter) ObserveLookup(database, result string) {}
+func (o *acquireCounter) ObserveCache(cache string, hit bool)   {}
+
+func (o *acquireCounter) ObserveAcquire(time.Duration) {
+	o.acquires.Add(1)
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestCountryNetworks(t *testing.T) {
+	engine := geoiptest.NewEngine(t)
+
+	result, err := engine.CountryNetworks("JP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相邻的 203.0.113.0/25 与 203.0.113.128/25 合并为一个网段
+	if !slices.Equal(result.IPv4, []string{"203.0.113.0/24"}) || len(result.IPv6) != 0 {
+		t.Errorf("JP = %+v", result)
+	}
+
+	result, err = engine.CountryNetworks("US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.IPv4, []string{"8.8.8.0/24"}) || !slices.Equal(result.IPv6, []string{"2001:4860::/32"}) {
+		t.Errorf("US = %+v", result)
+	}
+}
+
+// 并发请求同一国家时只遍历一次数据库, Reload 之后重新遍历
+func TestCountryNetworksDeduplicatesBuilds(t *testing.T) {
+	observer := &acquireCounter{}
+	opts := geoiptest.Options(t)
+	opts.Observer = observer
+	engine, err := geoip.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	concurrent := func() {
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for range 32 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if _, err := engine.CountryNetworks("JP"); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+	}
+
+	concurrent()
+	if n := observer.acquires.Load(); n != 1 {
+		t.Errorf("32 concurrent requests walked the database %d times, want 1", n)
+	}
+
+	if err := engine.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	concurrent()
+	if n := observer.acquires.Load(); n != 2 {
+		t.Errorf("walked the database %d times after reload, want 2", n)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	engine := geoiptest.NewEngine(t)
+	all := geoip.NetworkDatabases
+
+	tests := []struct {
+		name          string
+		prefix        string
+		databases     []string
+		offset, limit int
+		want          []string // database network
+		next          int      // 0 表示没有下一页
+	}{
+		{"first page", "203.0.113.0/24", []string{geoip.DatabaseCity}, 0, 1, []string{"city 203.0.113.0/25"}, 1},
+		{"last page", "203.0.113.0/24", []string{geoip.DatabaseCity}, 1, 1, []string{"city 203.0.113.128/25"}, 0},
+		{"offset past the end", "203.0.113.0/24", []string{geoip.DatabaseCity}, 5, 10, nil, 0},
+		{"across databases", "1.0.1.0/24", all, 0, 10, []string{"city 1.0.1.0/24", "cn 1.0.1.0/24"}, 0},
+		{"page ends inside a database", "1.0.1.0/24", all, 0, 1, []string{"city 1.0.1.0/24"}, 1},
+		// GeoCN 只包含 IPv4, IPv6 网段跳过该数据库而不是返回错误
+		{"IPv6 with an IPv4-only database", "2001:4860::/32", all, 0, 10, []string{"city 2001:4860::/32", "asn 2001:4860::/32"}, 0},
+		{"IPv6 only in the IPv4-only database", "2001:4860::/32", []string{geoip.DatabaseCN}, 0, 10, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Networks(netip.MustParsePrefix(tt.prefix), tt.databases, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range result.Networks {
+				got = append(got, entry.Database+" "+entry.Network)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("networks = %q, want %q", got, tt.want)
+			}
+			next := 0
+			if result.NextOffset != nil {
+				next = *result.NextOffset
+			}
+			if next != tt.next {
+				t.Errorf("next offset = %d, want %d", next, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ParseAddr 解析 IP 字符串, 返回去除 IPv4 映射 (::ffff:1.2.3.4) 与 IPv6 区域标识后的规范地址.
+// strict 为 true 时只接受 netip.ParseAddr 的标准写法且不允许区域标识;
+// 为 false 时还接受首尾空白、[2001:db8::1] 形式、fe80::1%eth0 形式及 IPv4 各段的前导零 (按十进制解释, 如 010.001.002.003)
+func ParseAddr(s string, strict bool) (netip.Addr, error) {
+	input := s
+	if !strict {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			s = s[1 : len(s)-1]
+		}
+		s = trimIPv4LeadingZeros(s)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: %q", ErrInvalidAddr, input)
+	}
+	if strict && addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("%w: %q", ErrInvalidAddr, input)
+	}
+	return addr.Unmap().WithZone(""), nil
+}
+
+// Version 返回地址族, 4 或 6
+func Version(addr netip.Addr) int {
+	if addr.Is4() {
+		return 4
+	}
+	return 6
+}
+
+// trimIPv4LeadingZeros 去掉点分十进制各段的前导零, 包括 IPv6 中内嵌的 IPv4 部分; 不是合法的四段十进制时原样返回
+func trimIPv4LeadingZeros(s string) string {
+	prefix, dotted := "", s
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		prefix, dotted = s[:i+1], s[i+1:]
+	}
+	zone := ""
+	if i := strings.IndexByte(dotted, '%'); i >= 0 {
+		dotted, zone = dotted[:i], dotted[i:]
+	}
+
+	parts := strings.Split(dotted, ".")
+	if len(parts) != 4 {
+		return s
+	}
+	for i, part := range parts {
+		if part == "" || len(part) > 3 || strings.Trim(part, "0123456789") != "" {
+			return s
+		}
+		n, _ := strconv.Atoi(part)
+		parts[i] = strconv.Itoa(n)
+	}
+	return prefix + strings.Join(parts, ".") + zone
+}
@@ -0,0 +1,52 @@
+package geoip_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"go-geoip/geoip"
+)
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string // 空字符串表示解析失败
+		strict string // 严格模式下的结果
+	}{
+		{"8.8.8.8", "8.8.8.8", "8.8.8.8"},
+		{"2001:4860:4860::8888", "2001:4860:4860::8888", "2001:4860:4860::8888"},
+		{"2001:4860:4860:0:0:0:0:8888", "2001:4860:4860::8888", "2001:4860:4860::8888"},
+		{"::ffff:1.2.3.4", "1.2.3.4", "1.2.3.4"},
+		{" 8.8.8.8\n", "8.8.8.8", ""},
+		{"[2001:db8::1]", "2001:db8::1", ""},
+		{"fe80::1%eth0", "fe80::1", ""},
+		{"010.001.002.003", "10.1.2.3", ""},
+		{"::ffff:010.001.002.003", "10.1.2.3", ""},
+		{"0.0.0.0", "0.0.0.0", "0.0.0.0"},
+		{"256.1.1.1", "", ""},
+		{"0256.1.1.1", "", ""},
+		{"1.2.3", "", ""},
+		{"[8.8.8.8", "", ""},
+		{"example.com", "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			want := tt.want
+			if strict {
+				want = tt.strict
+			}
+			got, err := geoip.ParseAddr(tt.in, strict)
+			if want == "" {
+				if !errors.Is(err, geoip.ErrInvalidAddr) {
+					t.Errorf("ParseAddr(%q, %v) = %v, %v, want ErrInvalidAddr", tt.in, strict, got, err)
+				}
+				continue
+			}
+			if err != nil || got != netip.MustParseAddr(want) {
+				t.Errorf("ParseAddr(%q, %v) = %v, %v, want %s", tt.in, strict, got, err, want)
+			}
+		}
+	}
+}
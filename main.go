@@ -1,22 +1,42 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
-	"github.com/oschwald/maxminddb-golang"
 	"go-geoip/common"
+	"go-geoip/common/apikey"
+	"go-geoip/common/certs"
+	"go-geoip/common/cloud"
 	"go-geoip/common/config"
 	logger "go-geoip/common/loggger"
+	"go-geoip/common/metrics"
+	"go-geoip/common/rdns"
+	"go-geoip/common/security"
+	"go-geoip/common/tracing"
+	"go-geoip/controller"
+	"go-geoip/geoip"
 	"go-geoip/middleware"
 	"go-geoip/router"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -26,12 +46,35 @@ const (
 	sessionName      = "session"
 )
 
+// @title go-geoip
+// @description IP 地理位置查询服务. 创建过密钥或配置了 API_SECRET 后, 标注了 Security 的接口需要携带密钥, 三种方式任选其一
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer <密钥或 JWT>
+// @securityDefinitions.apikey ApiKeyHeader
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey ApiKeyQuery
+// @in query
+// @name key
+// @securityDefinitions.apikey HMACSignature
+// @in header
+// @name X-Signature
+// @description HMAC-SHA256 签名, 同时需要 X-Signature-Key、X-Signature-Timestamp 与 X-Signature-Nonce, 见 README
 func main() {
+	common.ParseFlags()
+	if args := flag.Args(); len(args) > 0 {
+		runCommand(args)
+		return
+	}
+
 	logger.SetupLogger()
 	logger.SysLog(fmt.Sprintf("go-geoip %s started", common.Version))
 
 	setGinMode()
 	logDebugMode()
+	setupTracing()
 
 	server := setupServer()
 
@@ -46,6 +89,24 @@ func setGinMode() {
 	}
 }
 
+func setupTracing() {
+	if err := tracing.Setup(context.Background()); err != nil {
+		logger.FatalLog(fmt.Sprintf("failed to set up tracing: %v", err))
+	}
+	if config.OTLPEndpoint != "" {
+		logger.SysLog(fmt.Sprintf("Exporting traces to %s", config.OTLPEndpoint))
+	}
+}
+
+// shutdownTracing 退出前导出缓冲中的 span
+func shutdownTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		logger.SysError(fmt.Sprintf("failed to flush traces: %v", err))
+	}
+}
+
 func logDebugMode() {
 	if config.DebugEnabled {
 		logger.SysLog("running in debug mode")
@@ -53,6 +114,25 @@ func logDebugMode() {
 }
 
 func setupServer() *gin.Engine {
+	if _, err := apikey.Default(); err != nil {
+		logger.FatalLog(fmt.Sprintf("Failed to load API keys from %s: %v", config.APIKeysFile, err))
+	}
+	ledger, err := apikey.DefaultLedger()
+	if err != nil {
+		logger.FatalLog(fmt.Sprintf("Failed to load usage ledger from %s: %v", config.UsageFile, err))
+	}
+	// 退出前写回用量账本并导出剩余的 span, 避免丢失最后一次定期写回之后的记录
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+		if err := ledger.Flush(); err != nil {
+			logger.SysError(fmt.Sprintf("failed to save usage ledger: %v", err))
+		}
+		shutdownTracing()
+		os.Exit(0)
+	}()
+
 	server := gin.New()
 	server.Use(gin.Recovery(), middleware.RequestId())
 	middleware.SetUpLogger(server)
@@ -64,11 +144,45 @@ func setupServer() *gin.Engine {
 	return server
 }
 
+// runServer 在 PORT 上同时监听 IPv4 与 IPv6, 另外按配置在 LISTEN_IPV4/LISTEN_IPV6 上单独监听各地址族
 func runServer(server *gin.Engine) {
-	port := getPort()
-	if err := server.Run(":" + port); err != nil {
-		logger.FatalLog("failed to start HTTP server: %v", err)
+	listeners := []struct{ network, addr string }{{"tcp", ":" + getPort()}}
+	if config.ListenIPv4 != "" {
+		listeners = append(listeners, struct{ network, addr string }{"tcp4", config.ListenIPv4})
 	}
+	if config.ListenIPv6 != "" {
+		listeners = append(listeners, struct{ network, addr string }{"tcp6", config.ListenIPv6})
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLSCertFile != "" {
+		reloader, err := certs.New(config.TLSCertFile, config.TLSKeyFile, config.TLSClientCAFile, config.TLSClientCertRequired)
+		if err != nil {
+			logger.FatalLog(fmt.Sprintf("failed to load TLS certificate: %v", err))
+		}
+		tlsConfig = reloader.TLSConfig()
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		ln, err := net.Listen(l.network, l.addr)
+		if err != nil {
+			logger.FatalLog(fmt.Sprintf("failed to listen on %s %s: %v", l.network, l.addr, err))
+		}
+		if tlsConfig != nil {
+			logger.SysLog(fmt.Sprintf("Listening on %s %s (TLS)", l.network, ln.Addr()))
+			go func() {
+				srv := &http.Server{Handler: server, TLSConfig: tlsConfig}
+				errs <- srv.ServeTLS(ln, "", "")
+			}()
+			continue
+		}
+		logger.SysLog(fmt.Sprintf("Listening on %s %s", l.network, ln.Addr()))
+		go func() {
+			errs <- http.Serve(ln, server)
+		}()
+	}
+	logger.FatalLog(fmt.Sprintf("failed to start HTTP server: %v", <-errs))
 }
 
 func getPort() string {
@@ -78,12 +192,46 @@ func getPort() string {
 	return strconv.Itoa(*common.Port)
 }
 
-func loadDatabases() {
-	downloadAndSave("GeoIP-City.mmdb", getCityDBURL())
-	downloadAndSave("Geo-ASN.mmdb", getAsnDBURL())
-	downloadAndSave("GeoCN.mmdb", getCnDBURL())
+// loadDatabases 每次更新作为一条独立的链路, 下载与打开数据库为其子 span.
+// 返回继续使用旧文件的下载失败与重新加载失败, 由调用方决定是否退出
+func loadDatabases(opts geoip.Options) error {
+	ctx, span := tracing.Tracer().Start(context.Background(), "geoip.update")
+	defer span.End()
+
+	errs := []error{
+		downloadAndSave(ctx, common.CityDBFile, getCityDBURL()),
+		downloadAndSave(ctx, common.AsnDBFile, getAsnDBURL()),
+		downloadAndSave(ctx, common.CnDBFile, getCnDBURL()),
+	}
+	if config.AnonymousDBRemoteUrl != "" {
+		errs = append(errs, downloadAndSave(ctx, common.AnonymousDBFile, config.AnonymousDBRemoteUrl))
+	}
 
-	openDatabases()
+	errs = append(errs, openDatabases(ctx, opts))
+	return errors.Join(errs...)
+}
+
+// engineOptions 按环境变量构造查询引擎的配置
+func engineOptions() geoip.Options {
+	opts := geoip.Options{
+		CityDB:   common.CityDBFile,
+		ASNDB:    common.AsnDBFile,
+		CNDB:     common.CnDBFile,
+		Resolver: rdns.Default(),
+		ASNIndex: true,
+		Observer: metrics.EngineObserver{},
+	}
+	if config.AnonymousDBRemoteUrl != "" {
+		opts.AnonymousDB = common.AnonymousDBFile
+	}
+	// 安全列表与云厂商 IP 段作为 Provider, 随数据库一起重新加载
+	if lists := security.FromConfig(); lists.Enabled() {
+		opts.Providers = append(opts.Providers, lists)
+	}
+	if ranges := cloud.FromConfig(); ranges.Enabled() {
+		opts.Providers = append(opts.Providers, ranges)
+	}
+	return opts
 }
 
 func getCityDBURL() string {
@@ -107,49 +255,121 @@ func getCnDBURL() string {
 	return cnDBURL
 }
 
-func downloadAndSave(filename, url string) {
+// downloadAndSave 下载失败时若已有旧文件则继续使用并返回错误, 否则退出
+func downloadAndSave(ctx context.Context, filename, url string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "geoip.download", trace.WithAttributes(
+		attribute.String("geoip.file", filename),
+		semconv.URLFull(redactURL(url)),
+	))
+	defer span.End()
+
 	logger.SysLog(fmt.Sprintf("Downloading %s from %s...", filename, url))
-	resp, err := http.Get(url)
+	start := time.Now()
+	err := download(ctx, filename, url)
+	metrics.DownloadDuration.WithLabelValues(filename).Observe(time.Since(start).Seconds())
+	if err == nil {
+		logger.SysLog(fmt.Sprintf("Downloaded and saved %s successfully", filename))
+		return nil
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	metrics.DownloadFailures.WithLabelValues(filename).Inc()
+	if _, statErr := os.Stat(filename); statErr == nil {
+		logger.SysError(fmt.Sprintf("Failed to download %s, keeping the existing file: %v", filename, err))
+		return fmt.Errorf("download %s: %w", filename, err)
+	}
+	// FatalLog 直接退出, 不会执行 defer
+	span.End()
+	shutdownTracing()
+	logger.FatalLog(fmt.Sprintf("Failed to download %s: %v", filename, err))
+	return err
+}
+
+// redactURL 去掉数据库地址中的用户信息与查询参数 (如 license_key), 避免随 span 导出
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
+
+func download(ctx context.Context, filename, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		logger.FatalLog("Failed to download %s: %v", filename, err)
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
 
-	out, err := os.Create(filename)
+	// 先写入临时文件再重命名, 引擎中仍在使用的旧文件不会被截断
+	tmp := filename + ".tmp"
+	out, err := os.Create(tmp)
 	if err != nil {
-		logger.FatalLog("Failed to create file %s: %v", filename, err)
+		return err
 	}
 	defer out.Close()
 
 	if _, err := io.Copy(out, resp.Body); err != nil {
-		logger.FatalLog("Failed to save file %s: %v", filename, err)
+		return err
 	}
-	logger.SysLog(fmt.Sprintf("Downloaded and saved %s successfully", filename))
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
 }
 
-func openDatabases() {
-	var err error
-	common.Mu.Lock()
-	defer common.Mu.Unlock()
-
-	common.CityReader, err = maxminddb.Open("GeoIP-City.mmdb")
-	if err != nil {
-		logger.FatalLog("Error opening city database: %v", err)
+// engine 首次加载数据库时创建, 之后的更新调用 Reload
+var engine *geoip.Engine
+
+// openDatabases 首次打开失败时退出; 重新加载失败时继续使用旧数据库并返回错误
+func openDatabases(ctx context.Context, opts geoip.Options) error {
+	ctx, span := tracing.Tracer().Start(ctx, "geoip.open", trace.WithAttributes(attribute.Bool("geoip.reload", engine != nil)))
+	defer span.End()
+
+	if engine != nil {
+		// 打开失败时继续使用旧数据库
+		if err := engine.Reload(ctx); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			logger.SysError(fmt.Sprintf("Error reloading databases: %v", err))
+			return fmt.Errorf("reload databases: %w", err)
+		}
+		return nil
 	}
 
-	common.AsnReader, err = maxminddb.Open("Geo-ASN.mmdb")
-	if err != nil {
-		logger.FatalLog("Error opening ASN database: %v", err)
+	var err error
+	if engine, err = geoip.NewContext(ctx, opts); err != nil {
+		logger.FatalLog(fmt.Sprintf("Error opening databases: %v", err))
 	}
+	engine.OnReload(logASNIndex)
+	logASNIndex()
+	controller.SetEngine(engine)
+	metrics.RegisterDatabaseInfo(engine.Info)
+	if opts.Resolver != nil {
+		metrics.RegisterCacheStats("rdns", opts.Resolver.Stats)
+	}
+	return nil
+}
 
-	common.CnReader, err = maxminddb.Open("GeoCN.mmdb")
-	if err != nil {
-		logger.FatalLog("Error opening CN database: %v", err)
+func logASNIndex() {
+	if index, err := engine.ASNIndex(); err == nil && index != nil {
+		logger.SysLog(fmt.Sprintf("Built ASN index with %d autonomous systems", index.Len()))
 	}
 }
 
 func scheduleDatabaseUpdate() {
-	loadDatabases()
+	// 服务运行中的更新失败已记录日志, 继续使用旧数据库
+	_ = loadDatabases(engineOptions())
 
 	for {
 		nextUpdateTime := getNextSundayLastSecond()
@@ -160,7 +380,7 @@ func scheduleDatabaseUpdate() {
 		<-timer.C
 
 		logger.SysLog("Updating databases...")
-		loadDatabases()
+		_ = loadDatabases(engineOptions())
 	}
 }
 
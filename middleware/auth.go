@@ -1,37 +1,119 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/gin-gonic/gin"
-	"github.com/samber/lo"
 	"go-geoip/common"
+	"go-geoip/common/apikey"
 	"go-geoip/common/config"
-	"net/http"
-	"strings"
+	logger "go-geoip/common/loggger"
 )
 
-func isValidSecret(secret string) bool {
-	return config.ApiSecret != "" && !lo.Contains(config.ApiSecrets, secret)
+// apiKeyContextKey gin 上下文中保存已认证密钥的 key
+const apiKeyContextKey = "api_key"
+
+// 密钥的传递方式, 按优先级依次为 Authorization: Bearer、X-API-Key 请求头与 ?key= 查询参数
+const (
+	APIKeyHeader = "X-API-Key"
+	APIKeyQuery  = "key"
+)
+
+// credential 返回请求中携带的密钥, 浏览器嵌入等无法设置请求头的场景可使用查询参数
+func credential(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		if secret, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(secret)
+		}
+		return strings.TrimSpace(auth)
+	}
+	if secret := c.GetHeader(APIKeyHeader); secret != "" {
+		return strings.TrimSpace(secret)
+	}
+	return c.Query(APIKeyQuery)
+}
+
+// jwtAuth 配置了 JWT 密钥时非 nil
+var jwtAuth *jwtVerifier
+
+// authEnabled 存在 API 密钥或配置了 JWT/HMAC/mTLS 鉴权时, 受保护的路由必须携带凭证
+func authEnabled(store *apikey.Store) bool {
+	return store.Enabled() || jwtAuth != nil || len(config.HMACKeys) > 0 || config.TLSClientCAFile != ""
+}
+
+// authenticate 依次尝试 HMAC 签名、API 密钥与 JWT, 都未携带时使用客户端证书; 未携带任何凭证时返回 nil, nil
+func authenticate(c *gin.Context, store *apikey.Store) (*apikey.Key, error) {
+	if isSignedRequest(c) {
+		return verifySignature(c)
+	}
+	secret := credential(c)
+	if secret == "" {
+		return clientCertKey(c), nil
+	}
+	key, err := store.Authenticate(secret)
+	if errors.Is(err, apikey.ErrInvalidKey) && jwtAuth != nil && strings.Count(secret, ".") == 2 {
+		return jwtAuth.Verify(secret)
+	}
+	return key, err
 }
 
+// authHelper 识别请求携带的凭证, 按密钥的限流与配额由 RateLimit 执行; 是否必须携带凭证由路由上的 RequireScope 决定.
+// 启用鉴权后携带无效凭证返回 401, 未启用时按匿名请求处理
 func authHelper(c *gin.Context) {
-	secret := c.Request.Header.Get("Authorization")
-	secret = strings.Replace(secret, "Bearer ", "", 1)
-	if isValidSecret(secret) {
-		common.SendResponse(c, http.StatusUnauthorized, 1, "auth fail", nil)
-		c.Abort()
+	store, _ := apikey.Default()
+	key, err := authenticate(c, store)
+	if err != nil {
+		if !authEnabled(store) {
+			c.Next()
+			return
+		}
+		common.AbortWithError(c, common.ErrUnauthorized.WithDetail("%s", err.Error()))
 		return
 	}
-
-	if config.ApiSecret == "" {
-		c.Request.Header.Set("Authorization", "")
+	if key != nil {
+		c.Set(apiKeyContextKey, key)
 	}
-
 	c.Next()
-	return
 }
 
 func Auth() func(c *gin.Context) {
+	verifier, err := newJWTVerifier()
+	if err != nil {
+		logger.FatalLog(fmt.Sprintf("JWT 配置错误: %v", err))
+	}
+	jwtAuth = verifier
 	return func(c *gin.Context) {
 		authHelper(c)
 	}
 }
+
+// CurrentKey 返回本次请求认证通过的密钥, 未携带密钥时为 nil
+func CurrentKey(c *gin.Context) *apikey.Key {
+	if v, ok := c.Get(apiKeyContextKey); ok {
+		return v.(*apikey.Key)
+	}
+	return nil
+}
+
+// RequireScope 启用鉴权后要求携带密钥, 密钥缺少 scope 时返回 403; scope 为空时任意有效密钥均可.
+// admin 始终需要密钥, 即使未启用鉴权
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := CurrentKey(c)
+		if key == nil {
+			if scope == apikey.ScopeAdmin {
+				common.AbortWithError(c, common.ErrUnauthorized.WithDetail("admin key required"))
+				return
+			}
+			if store, _ := apikey.Default(); authEnabled(store) {
+				common.AbortWithError(c, common.ErrUnauthorized.WithDetail("API key required"))
+			}
+			return
+		}
+		if scope != "" && !key.HasScope(scope) {
+			common.AbortWithError(c, common.ErrForbidden.WithDetail("scope %q required", scope))
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 同时携带多种凭证时按 Authorization > X-API-Key > ?key= 的顺序取用
+func TestCredential(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name          string
+		authorization string
+		header        string
+		query         string
+		want          string
+	}{
+		{"none", "", "", "", ""},
+		{"bearer", "Bearer bearer-secret", "", "", "bearer-secret"},
+		{"bearer with spaces", "Bearer  bearer-secret ", "", "", "bearer-secret"},
+		{"authorization without scheme", "raw-secret", "", "", "raw-secret"},
+		{"header", "", "header-secret", "", "header-secret"},
+		{"header with spaces", "", " header-secret ", "", "header-secret"},
+		{"query", "", "", "key=query-secret", "query-secret"},
+		{"encoded query", "", "", "key=gk_a%2Eb", "gk_a.b"},
+		{"bearer over header", "Bearer bearer-secret", "header-secret", "", "bearer-secret"},
+		{"bearer over query", "Bearer bearer-secret", "", "key=query-secret", "bearer-secret"},
+		{"header over query", "", "header-secret", "key=query-secret", "header-secret"},
+		{"all three", "Bearer bearer-secret", "header-secret", "key=query-secret", "bearer-secret"},
+		{"repeated query uses the first", "", "", "key=first&key=second", "first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/ip/8.8.8.8?"+tt.query, nil)
+			if tt.authorization != "" {
+				c.Request.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.header != "" {
+				c.Request.Header.Set(APIKeyHeader, tt.header)
+			}
+			if got := credential(c); got != tt.want {
+				t.Errorf("credential = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
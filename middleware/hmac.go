@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+)
+
+// HMAC 签名请求使用的请求头; 签名为 hex(HMAC-SHA256(secret, 待签字符串)), 待签字符串为
+// 方法、RequestURI (含查询参数)、时间戳 (Unix 秒)、nonce 与请求体 SHA-256 (hex) 以换行连接
+const (
+	SignatureKeyHeader       = "X-Signature-Key"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureHeader          = "X-Signature"
+)
+
+// 参与签名的请求体上限, 超出时拒绝
+const maxSignedBody = 1 << 20
+
+var (
+	errSignatureHeaders = errors.New("incomplete signature headers")
+	errSignatureKey     = errors.New("unknown signature key")
+	errSignatureExpired = errors.New("signature timestamp outside replay window")
+	errSignatureReplay  = errors.New("signature nonce already used")
+	errSignatureInvalid = errors.New("invalid signature")
+	errSignatureBody    = errors.New("request body too large to sign")
+)
+
+// nonceCache 记录重放窗口内已使用的 nonce
+type nonceCache struct {
+	mutex sync.Mutex
+	seen  map[string]int64
+	sweep int64
+}
+
+var usedNonces = nonceCache{seen: map[string]int64{}}
+
+// use nonce 在 expires 之前首次出现时返回 true
+func (n *nonceCache) use(nonce string, now, expires int64) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if now >= n.sweep {
+		for k, exp := range n.seen {
+			if exp <= now {
+				delete(n.seen, k)
+			}
+		}
+		n.sweep = now + int64(config.HMACReplayWindow)
+	}
+	if exp, ok := n.seen[nonce]; ok && exp > now {
+		return false
+	}
+	n.seen[nonce] = expires
+	return true
+}
+
+// isSignedRequest 携带签名头的请求按 HMAC 方式鉴权
+func isSignedRequest(c *gin.Context) bool {
+	return c.GetHeader(SignatureHeader) != ""
+}
+
+// verifySignature 校验签名与时间戳, 同一 nonce 在重放窗口内只能使用一次; 签名密钥拥有除 admin 外的全部权限
+func verifySignature(c *gin.Context) (*apikey.Key, error) {
+	id := c.GetHeader(SignatureKeyHeader)
+	timestamp := c.GetHeader(SignatureTimestampHeader)
+	nonce := c.GetHeader(SignatureNonceHeader)
+	signature := c.GetHeader(SignatureHeader)
+	if id == "" || timestamp == "" || nonce == "" || signature == "" {
+		return nil, errSignatureHeaders
+	}
+
+	secret, ok := hmacSecret(id)
+	if !ok {
+		return nil, errSignatureKey
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errSignatureHeaders
+	}
+	now := time.Now().Unix()
+	window := int64(config.HMACReplayWindow)
+	if ts < now-window || ts > now+window {
+		return nil, errSignatureExpired
+	}
+
+	bodyHash, err := hashBody(c)
+	if err != nil {
+		return nil, err
+	}
+	payload := strings.Join([]string{c.Request.Method, c.Request.RequestURI, timestamp, nonce, bodyHash}, "\n")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return nil, errSignatureInvalid
+	}
+
+	// 签名有效后才记录 nonce, 避免伪造请求占用他人的 nonce
+	if !usedNonces.use(id+":"+nonce, now, ts+window) {
+		return nil, errSignatureReplay
+	}
+	return &apikey.Key{ID: "hmac:" + id, Label: id, Scopes: []string{apikey.ScopeAll}}, nil
+}
+
+// hashBody 读取请求体计算摘要后放回, 供后续 handler 继续读取
+func hashBody(c *gin.Context) (string, error) {
+	body := []byte{}
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxSignedBody+1))
+		if err != nil {
+			return "", err
+		}
+		if len(body) > maxSignedBody {
+			return "", errSignatureBody
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func hmacSecret(id string) (string, bool) {
+	for _, item := range config.HMACKeys {
+		if name, secret, ok := strings.Cut(item, ":"); ok && name == id {
+			return secret, true
+		}
+	}
+	return "", false
+}
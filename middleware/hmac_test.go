@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+)
+
+type signedRequest struct {
+	method, uri, body string
+	key, secret       string
+	timestamp         int64
+	nonce             string
+}
+
+func (r signedRequest) context(t *testing.T) *gin.Context {
+	t.Helper()
+	bodySum := sha256.Sum256([]byte(r.body))
+	timestamp := strconv.FormatInt(r.timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(r.secret))
+	mac.Write([]byte(strings.Join([]string{r.method, r.uri, timestamp, r.nonce, hex.EncodeToString(bodySum[:])}, "\n")))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(r.method, r.uri, strings.NewReader(r.body))
+	c.Request.Header.Set(SignatureKeyHeader, r.key)
+	c.Request.Header.Set(SignatureTimestampHeader, timestamp)
+	c.Request.Header.Set(SignatureNonceHeader, r.nonce)
+	c.Request.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return c
+}
+
+func TestVerifySignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(keys []string, window int) {
+		config.HMACKeys, config.HMACReplayWindow = keys, window
+	}(config.HMACKeys, config.HMACReplayWindow)
+	config.HMACKeys = []string{"web:s3cret", "other:0ther"}
+	config.HMACReplayWindow = 300
+
+	now := time.Now().Unix()
+	valid := signedRequest{
+		method: "POST", uri: "/ip/batch?strict=1", body: `{"ips":["8.8.8.8"]}`,
+		key: "web", secret: "s3cret", timestamp: now, nonce: "n-valid",
+	}
+
+	c := valid.context(t)
+	key, err := verifySignature(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != "hmac:web" || key.HasScope(apikey.ScopeAdmin) {
+		t.Errorf("key = %+v, want hmac:web without admin", key)
+	}
+	// 计算摘要后请求体仍可被 handler 读取
+	if body, _ := io.ReadAll(c.Request.Body); string(body) != valid.body {
+		t.Errorf("body after verification = %q", body)
+	}
+
+	// 同一 nonce 在窗口内只能使用一次, 其他密钥的相同 nonce 不受影响
+	if _, err := verifySignature(valid.context(t)); !errors.Is(err, errSignatureReplay) {
+		t.Errorf("replayed nonce: %v, want %v", err, errSignatureReplay)
+	}
+	other := valid
+	other.key, other.secret = "other", "0ther"
+	if _, err := verifySignature(other.context(t)); err != nil {
+		t.Errorf("same nonce with another key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		sign   func(r *signedRequest) // 签名前修改请求
+		tamper func(c *gin.Context)   // 签名后修改请求
+		want   error
+	}{
+		{name: "wrong secret", sign: func(r *signedRequest) { r.secret = "guess" }, want: errSignatureInvalid},
+		{name: "expired", sign: func(r *signedRequest) { r.timestamp = now - 301 }, want: errSignatureExpired},
+		{name: "from the future", sign: func(r *signedRequest) { r.timestamp = now + 301 }, want: errSignatureExpired},
+		{name: "unknown key", tamper: func(c *gin.Context) { c.Request.Header.Set(SignatureKeyHeader, "nobody") }, want: errSignatureKey},
+		{name: "missing nonce", tamper: func(c *gin.Context) { c.Request.Header.Del(SignatureNonceHeader) }, want: errSignatureHeaders},
+		{name: "malformed timestamp", tamper: func(c *gin.Context) { c.Request.Header.Set(SignatureTimestampHeader, "soon") }, want: errSignatureHeaders},
+		{name: "tampered query", tamper: func(c *gin.Context) { c.Request.RequestURI = "/ip/batch?strict=0" }, want: errSignatureInvalid},
+		{name: "tampered body", tamper: func(c *gin.Context) {
+			c.Request.Body = io.NopCloser(strings.NewReader(`{"ips":["1.1.1.1"]}`))
+		}, want: errSignatureInvalid},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			r.nonce = "n-" + strconv.Itoa(i)
+			if tt.sign != nil {
+				tt.sign(&r)
+			}
+			c := r.context(t)
+			if tt.tamper != nil {
+				tt.tamper(c)
+			}
+			if _, err := verifySignature(c); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
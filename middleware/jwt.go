@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+)
+
+// jwtMethods 只接受这些签名算法, 防止以 none 或用公钥作为 HS256 密钥的攻击
+var jwtMethods = []string{"HS256", "RS256", "EdDSA"}
+
+// jwtVerifier 按 JWT_* 配置校验网关签发的 JWT; JWKS 文件修改后自动重新加载
+type jwtVerifier struct {
+	secret []byte
+	static []jwk
+
+	mutex    sync.Mutex
+	jwks     []jwk
+	jwksTime time.Time
+}
+
+// jwk 一个可用于验签的密钥, kid 为空时与任意 kid 匹配
+type jwk struct {
+	kid string
+	key crypto.PublicKey
+}
+
+// newJWTVerifier 未配置任何 JWT 密钥时返回 nil
+func newJWTVerifier() (*jwtVerifier, error) {
+	v := &jwtVerifier{}
+	if config.JWTSecret != "" {
+		v.secret = []byte(config.JWTSecret)
+	}
+	for _, path := range config.JWTPublicKeys {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PUBLIC_KEYS %s: %w", path, err)
+		}
+		v.static = append(v.static, jwk{key: key})
+	}
+	if config.JWTJWKSFile != "" {
+		if _, err := v.jwksKeys(); err != nil {
+			return nil, fmt.Errorf("JWT_JWKS_FILE: %w", err)
+		}
+	}
+	if v.secret == nil && v.static == nil && config.JWTJWKSFile == "" {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// Verify 校验签名、exp (必须存在) 与 aud/iss (已配置时), 并把声明映射为密钥 (不含 admin 权限)
+func (v *jwtVerifier) Verify(token string) (*apikey.Key, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(jwtMethods), jwt.WithExpirationRequired()}
+	if config.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(config.JWTAudience))
+	}
+	if config.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.JWTIssuer))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, v.keyFunc, opts...); err != nil {
+		return nil, err
+	}
+
+	sub, _ := claims.GetSubject()
+	tier, _ := claims[config.JWTTierClaim].(string)
+	// 网关签发的令牌不能获得 admin 权限, 管理接口只接受密钥存储中的 admin 密钥
+	scopes := slices.DeleteFunc(claimStrings(claims[config.JWTScopeClaim]), func(scope string) bool {
+		return scope == apikey.ScopeAdmin
+	})
+	key := &apikey.Key{
+		ID:        "jwt:" + sub,
+		Label:     sub,
+		Scopes:    scopes,
+		RateLimit: tierLimit(tier),
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		key.ExpiresAt = &exp.Time
+	}
+	return key, nil
+}
+
+func (v *jwtVerifier) keyFunc(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == "HS256" {
+		if v.secret == nil {
+			return nil, errors.New("HS256 is not configured")
+		}
+		return v.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	keys := v.static
+	if config.JWTJWKSFile != "" {
+		jwks, err := v.jwksKeys()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys[:len(keys):len(keys)], jwks...)
+	}
+
+	var candidates jwt.VerificationKeySet
+	for _, k := range keys {
+		if k.kid != "" && kid != "" && k.kid != kid {
+			continue
+		}
+		switch k.key.(type) {
+		case *rsa.PublicKey:
+			if token.Method.Alg() == "RS256" {
+				candidates.Keys = append(candidates.Keys, k.key)
+			}
+		case ed25519.PublicKey:
+			if token.Method.Alg() == "EdDSA" {
+				candidates.Keys = append(candidates.Keys, k.key)
+			}
+		}
+	}
+	if len(candidates.Keys) == 0 {
+		return nil, fmt.Errorf("no %s key for kid %q", token.Method.Alg(), kid)
+	}
+	return candidates, nil
+}
+
+// jwksKeys 文件修改时间变化时重新解析, 便于网关轮换密钥
+func (v *jwtVerifier) jwksKeys() ([]jwk, error) {
+	stat, err := os.Stat(config.JWTJWKSFile)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.jwks != nil && stat.ModTime().Equal(v.jwksTime) {
+		return v.jwks, nil
+	}
+	data, err := os.ReadFile(config.JWTJWKSFile)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	v.jwks, v.jwksTime = keys, stat.ModTime()
+	return keys, nil
+}
+
+// parseJWKS 支持 RSA 与 OKP (Ed25519) 公钥, 其余类型忽略
+func parseJWKS(data []byte) ([]jwk, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := []jwk{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("kid %q: %w", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("kid %q: %w", k.Kid, err)
+			}
+			pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			keys = append(keys, jwk{kid: k.Kid, key: pub})
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("kid %q: invalid Ed25519 key", k.Kid)
+			}
+			keys = append(keys, jwk{kid: k.Kid, key: ed25519.PublicKey(x)})
+		}
+	}
+	return keys, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return jwt.ParseEdPublicKeyFromPEM(data)
+}
+
+// claimStrings scope 声明可以是空格分隔的字符串 (OAuth 2.0) 或字符串数组
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// tierLimit 返回 RATE_LIMIT_TIERS 中 tier 对应的每个限流窗口的请求数, 未配置时为 0 (使用 REQUEST_RATE_LIMIT)
+func tierLimit(tier string) int {
+	for _, item := range config.RateLimitTiers {
+		name, limit, ok := strings.Cut(item, ":")
+		if ok && name == tier {
+			n, _ := strconv.Atoi(limit)
+			return n
+		}
+	}
+	return 0
+}
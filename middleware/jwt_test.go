@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+)
+
+// jwtTestKeys 测试用的签名密钥: rsaA/rsaB 以 kid a/b 写入 JWKS 文件, rsaPEM 与 ed 以 PEM 公钥配置
+type jwtTestKeys struct {
+	rsaA, rsaB, rsaPEM *rsa.PrivateKey
+	ed                 ed25519.PrivateKey
+	rsaPEMBytes        []byte
+}
+
+// setupJWT 按测试密钥设置 JWT_* 配置并创建 verifier, 测试结束后恢复原配置
+func setupJWT(t *testing.T) (*jwtVerifier, jwtTestKeys) {
+	t.Helper()
+	secret, publicKeys, jwksFile := config.JWTSecret, config.JWTPublicKeys, config.JWTJWKSFile
+	audience, issuer, tiers := config.JWTAudience, config.JWTIssuer, config.RateLimitTiers
+	t.Cleanup(func() {
+		config.JWTSecret, config.JWTPublicKeys, config.JWTJWKSFile = secret, publicKeys, jwksFile
+		config.JWTAudience, config.JWTIssuer, config.RateLimitTiers = audience, issuer, tiers
+	})
+
+	var keys jwtTestKeys
+	for _, key := range []**rsa.PrivateKey{&keys.rsaA, &keys.rsaB, &keys.rsaPEM} {
+		var err error
+		if *key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.ed = edKey
+
+	dir := t.TempDir()
+	keys.rsaPEMBytes = writePublicKeyPEM(t, filepath.Join(dir, "rsa.pem"), &keys.rsaPEM.PublicKey)
+	writePublicKeyPEM(t, filepath.Join(dir, "ed.pem"), edPub)
+
+	jwks := map[string]any{"keys": []map[string]string{
+		rsaJWK("a", &keys.rsaA.PublicKey),
+		rsaJWK("b", &keys.rsaB.PublicKey),
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	data, _ := json.Marshal(jwks)
+	if err := os.WriteFile(filepath.Join(dir, "jwks.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config.JWTSecret = "hs256-secret"
+	config.JWTPublicKeys = []string{filepath.Join(dir, "rsa.pem"), filepath.Join(dir, "ed.pem")}
+	config.JWTJWKSFile = filepath.Join(dir, "jwks.json")
+	config.JWTAudience, config.JWTIssuer = "geoip", "https://gw.example.com"
+	config.RateLimitTiers = []string{"free:60", "pro:600"}
+
+	verifier, err := newJWTVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier, keys
+}
+
+func writePublicKeyPEM(t *testing.T, path string, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTVerify(t *testing.T) {
+	verifier, keys := setupJWT(t)
+	now := time.Now()
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user-1", "aud": "geoip", "iss": "https://gw.example.com",
+			"exp": now.Add(time.Minute).Unix(), "scope": "lookup batch", "tier": "pro",
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"HS256", signToken(t, jwt.SigningMethodHS256, "", claims(nil), []byte("hs256-secret")), true},
+		{"RS256 from PEM", signToken(t, jwt.SigningMethodRS256, "", claims(nil), keys.rsaPEM), true},
+		{"EdDSA from PEM", signToken(t, jwt.SigningMethodEdDSA, "", claims(nil), keys.ed), true},
+		{"JWKS kid a", signToken(t, jwt.SigningMethodRS256, "a", claims(nil), keys.rsaA), true},
+		{"JWKS kid b", signToken(t, jwt.SigningMethodRS256, "b", claims(nil), keys.rsaB), true},
+		{"kid does not match the signing key", signToken(t, jwt.SigningMethodRS256, "a", claims(nil), keys.rsaB), false},
+		{"unknown kid", signToken(t, jwt.SigningMethodRS256, "c", claims(nil), keys.rsaA), false},
+		{"key with use=enc", signToken(t, jwt.SigningMethodRS256, "enc", claims(nil), keys.rsaA), false},
+		{"wrong HS256 secret", signToken(t, jwt.SigningMethodHS256, "", claims(nil), []byte("guess")), false},
+		{"alg none", signToken(t, jwt.SigningMethodNone, "", claims(nil), jwt.UnsafeAllowNoneSignatureType), false},
+		// 以 RS256 公钥的 PEM 内容作为 HS256 密钥签名 (算法混淆)
+		{"HS256 signed with the RS256 public key", signToken(t, jwt.SigningMethodHS256, "", claims(nil), keys.rsaPEMBytes), false},
+		{"HS384 is not accepted", signToken(t, jwt.SigningMethodHS384, "", claims(nil), []byte("hs256-secret")), false},
+		{"missing exp", signToken(t, jwt.SigningMethodHS256, "", claims(func(c jwt.MapClaims) { delete(c, "exp") }), []byte("hs256-secret")), false},
+		{"expired", signToken(t, jwt.SigningMethodHS256, "", claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }), []byte("hs256-secret")), false},
+		{"wrong aud", signToken(t, jwt.SigningMethodHS256, "", claims(func(c jwt.MapClaims) { c["aud"] = "billing" }), []byte("hs256-secret")), false},
+		{"missing aud", signToken(t, jwt.SigningMethodHS256, "", claims(func(c jwt.MapClaims) { delete(c, "aud") }), []byte("hs256-secret")), false},
+		{"wrong iss", signToken(t, jwt.SigningMethodHS256, "", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }), []byte("hs256-secret")), false},
+		{"malformed", "not.a.jwt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := verifier.Verify(tt.token)
+			if tt.ok && err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("Verify accepted the token: %+v", key)
+				}
+				return
+			}
+			if key.ID != "jwt:user-1" || !slices.Equal(key.Scopes, []string{"lookup", "batch"}) || key.RateLimit != 600 || key.ExpiresAt == nil {
+				t.Errorf("key = %+v", key)
+			}
+		})
+	}
+}
+
+// 未配置 JWT_SECRET 时, 以 RS256 公钥作为 HS256 密钥签名的令牌 (算法混淆) 同样被拒绝
+func TestJWTAlgorithmConfusion(t *testing.T) {
+	_, keys := setupJWT(t)
+	config.JWTSecret = ""
+	verifier, err := newJWTVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "user-1", "aud": "geoip", "iss": "https://gw.example.com", "exp": time.Now().Add(time.Minute).Unix()}
+	for _, kid := range []string{"", "a"} {
+		token := signToken(t, jwt.SigningMethodHS256, kid, claims, keys.rsaPEMBytes)
+		if key, err := verifier.Verify(token); err == nil {
+			t.Errorf("kid %q: accepted HS256 token signed with the public key: %+v", kid, key)
+		}
+	}
+	if _, err := verifier.Verify(signToken(t, jwt.SigningMethodRS256, "", claims, keys.rsaPEM)); err != nil {
+		t.Errorf("RS256: %v", err)
+	}
+}
+
+// scope 声明中的 admin 被忽略, 其余权限保留
+func TestJWTAdminScope(t *testing.T) {
+	verifier, _ := setupJWT(t)
+	for _, scope := range []any{"lookup admin", []any{"admin", "*"}} {
+		token := signToken(t, jwt.SigningMethodHS256, "", jwt.MapClaims{
+			"sub": "user-1", "aud": "geoip", "iss": "https://gw.example.com",
+			"exp": time.Now().Add(time.Minute).Unix(), "scope": scope,
+		}, []byte("hs256-secret"))
+		key, err := verifier.Verify(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.HasScope(apikey.ScopeAdmin) || slices.Contains(key.Scopes, apikey.ScopeAdmin) || len(key.Scopes) != 1 {
+			t.Errorf("scope %v: key scopes = %v, want admin removed", scope, key.Scopes)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"go-geoip/common/helper"
 )
@@ -19,7 +22,24 @@ func SetUpLogger(server *gin.Engine) {
 			param.Latency,
 			param.ClientIP,
 			param.Method,
-			param.Path,
+			redactPath(param.Path),
 		)
 	}))
 }
+
+// redactPath 隐藏查询参数中的密钥, 避免写入日志
+func redactPath(path string) string {
+	p, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return p + "?[unparsable]"
+	}
+	if !query.Has(APIKeyQuery) {
+		return path
+	}
+	query.Set(APIKeyQuery, "REDACTED")
+	return p + "?" + query.Encode()
+}
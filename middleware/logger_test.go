@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/ip/8.8.8.8", "/ip/8.8.8.8"},
+		{"/ip/8.8.8.8?rdns=1", "/ip/8.8.8.8?rdns=1"},
+		{"/ip/8.8.8.8?key=gk_a.b", "/ip/8.8.8.8?key=REDACTED"},
+		{"/ip/8.8.8.8?rdns=1&key=gk_a.b&lang=en", "/ip/8.8.8.8?key=REDACTED&lang=en&rdns=1"},
+		{"/ip/8.8.8.8?key=gk_a.b&key=gk_c.d", "/ip/8.8.8.8?key=REDACTED"},
+		{"/ip/8.8.8.8?key=gk_a%2Eb", "/ip/8.8.8.8?key=REDACTED"},
+		{"/ip/8.8.8.8?%6Bey=gk_a.b", "/ip/8.8.8.8?key=REDACTED"}, // 参数名经过编码
+		{"/ip/8.8.8.8?key=", "/ip/8.8.8.8?key=REDACTED"},
+		{"/ip/8.8.8.8?keys=1", "/ip/8.8.8.8?keys=1"},
+		{"/ip/8.8.8.8?key=gk_a.b&x=%zz", "/ip/8.8.8.8?[unparsable]"},
+	}
+	for _, tt := range tests {
+		if got := redactPath(tt.path); got != tt.want {
+			t.Errorf("redactPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// 访问日志中不出现查询参数里的密钥, 无论原文还是编码后的形式
+func TestAccessLogRedactsKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(w io.Writer) { gin.DefaultWriter = w }(gin.DefaultWriter)
+	var buf bytes.Buffer
+	gin.DefaultWriter = &buf
+
+	engine := gin.New()
+	engine.Use(RequestId())
+	SetUpLogger(engine)
+	engine.GET("/ip/:ip", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	secret := "gk_0123456789abcdef.secretpart"
+	for _, query := range []string{
+		"key=" + secret,
+		"rdns=1&key=" + url.QueryEscape(secret) + "&lang=en",
+		"key=" + secret + "&key=" + secret,
+		"key=gk_0123456789abcdef%2Esecretpart",
+		"%6Bey=" + secret,
+		"key=" + secret + "&x=%zz",
+	} {
+		buf.Reset()
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ip/8.8.8.8?"+query, nil))
+		line := buf.String()
+		if line == "" {
+			t.Fatalf("%s: nothing logged", query)
+		}
+		if strings.Contains(line, "secretpart") {
+			t.Errorf("%s: secret in access log: %s", query, line)
+		}
+	}
+}
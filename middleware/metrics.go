@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/metrics"
+)
+
+// Metrics 按路由 (注册路径)、方法与状态码记录请求数与耗时; 未匹配路由的请求统一记为 unmatched, 避免标签数量无限增长
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := routeLabel(c)
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.RequestsTotal.WithLabelValues(route, c.Request.Method, status).Inc()
+		metrics.RequestDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/apikey"
+	"go-geoip/common/certs"
+	"go-geoip/common/config"
+)
+
+// clientCertKey 将已通过 CA 校验的客户端证书映射为密钥, 未提供证书时返回 nil.
+// 未配置 MTLS_IDENTITIES 时所有证书拥有除 admin 外的全部权限, 否则只拥有所列身份的权限, 未列出的身份同样返回 nil 按未携带凭证处理
+func clientCertKey(c *gin.Context) *apikey.Key {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	identity := certs.Identity(state.VerifiedChains[0][0])
+	scopes, ok := identityScopes(identity)
+	if !ok {
+		return nil
+	}
+	return &apikey.Key{ID: "mtls:" + identity, Label: identity, Scopes: scopes}
+}
+
+func identityScopes(identity string) ([]string, bool) {
+	if len(config.MTLSIdentities) == 0 {
+		return []string{apikey.ScopeAll}, true
+	}
+	for _, item := range config.MTLSIdentities {
+		// 身份本身可能包含冒号 (spiffe://), 以最后一个 = 分隔
+		i := strings.LastIndexByte(item, '=')
+		if i > 0 && item[:i] == identity {
+			return strings.Split(item[i+1:], "|"), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+)
+
+func TestClientCertKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(identities []string, caFile, keysFile string) {
+		config.MTLSIdentities, config.TLSClientCAFile, config.APIKeysFile = identities, caFile, keysFile
+	}(config.MTLSIdentities, config.TLSClientCAFile, config.APIKeysFile)
+	config.TLSClientCAFile = "ca.pem"
+	config.APIKeysFile = ""
+
+	web := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "mesh", Path: "/web"}}}
+	billing := &x509.Certificate{Subject: pkix.Name{CommonName: "billing.internal"}}
+	unknown := &x509.Certificate{DNSNames: []string{"unknown.internal"}}
+
+	engine := gin.New()
+	engine.Use(Auth())
+	engine.GET("/info", RequireScope(""), func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/ip/:ip", RequireScope(apikey.ScopeLookup), func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/export", RequireScope(apikey.ScopeExport), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		identities []string
+		cert       *x509.Certificate
+		path       string
+		want       int
+	}{
+		{"no identities configured", nil, unknown, "/export", http.StatusOK},
+		{"no certificate", nil, nil, "/info", http.StatusUnauthorized},
+		{"listed identity", []string{"spiffe://mesh/web=lookup|batch", "billing.internal=*"}, web, "/ip/8.8.8.8", http.StatusOK},
+		{"listed identity without scope", []string{"spiffe://mesh/web=lookup|batch"}, web, "/export", http.StatusForbidden},
+		{"wildcard identity", []string{"spiffe://mesh/web=lookup", "billing.internal=*"}, billing, "/export", http.StatusOK},
+		{"unlisted identity", []string{"spiffe://mesh/web=lookup"}, unknown, "/info", http.StatusUnauthorized},
+		{"unlisted identity on scoped route", []string{"spiffe://mesh/web=lookup"}, billing, "/ip/8.8.8.8", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.MTLSIdentities = tt.identities
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -1,36 +1,228 @@
 package middleware
 
 import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"go-geoip/common"
+	"go-geoip/common/apikey"
 	"go-geoip/common/config"
-	"net/http"
+	logger "go-geoip/common/loggger"
+	"go-geoip/common/metrics"
+	"go-geoip/common/ratelimit"
 )
 
-var timeFormat = "2006-01-02T15:04:05.000Z"
+// limiter 由 RateLimit 初始化
+var limiter ratelimit.Limiter
 
-var inMemoryRateLimiter common.InMemoryRateLimiter
+type rateLimitCheck struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// CostFunc 返回请求消耗的计费单位, 如批量查询的 IP 数; 未设置的路由每次请求计 1
+type CostFunc func(c *gin.Context) int
 
-func memoryRateLimiter(c *gin.Context, maxRequestNum int, duration int64, mark string) {
-	key := mark + c.ClientIP()
-	if !inMemoryRateLimiter.Request(key, maxRequestNum, duration) {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"success": false,
-			"message": "请求过于频繁,请稍后再试",
-		})
-		c.Abort()
-		return
+// RateLimit 按调用方限流: 携带凭证时按密钥 (或 JWT/mTLS 身份), 否则按客户端 IP; ROUTE_RATE_LIMITS 中的路由另外单独计数.
+// 令牌与每日配额都按 costs 中对应路由 (注册路径) 的计费单位扣除; 配额在处理前预留, 响应为 4xx/5xx (如缺少权限、参数错误) 时退还,
+// 只有成功的请求计入按月账本.
+// 响应中返回最严格一项的 RateLimit-Limit/RateLimit-Remaining/RateLimit-Reset, 被拒绝时返回 Retry-After
+func RateLimit(costs map[string]CostFunc) gin.HandlerFunc {
+	limiter = newLimiter()
+	allowlist := parseAllowlist(config.RateLimitAllowlist)
+	routeLimits := parseRouteLimits(config.RouteRateLimits)
+	window := time.Duration(config.RateLimitWindow) * time.Second
+	if err := validateLimits(window, routeLimits); err != nil {
+		logger.FatalLog(err.Error())
 	}
-}
+	ledger, _ := apikey.DefaultLedger()
 
-func rateLimitFactory(maxRequestNum int, duration int64, mark string) func(c *gin.Context) {
-	// It's safe to call multi times.
-	inMemoryRateLimiter.Init(config.RateLimitKeyExpirationDuration)
 	return func(c *gin.Context) {
-		memoryRateLimiter(c, maxRequestNum, duration, mark)
+		key := CurrentKey(c)
+		cost := 1
+		if costFunc, ok := costs[c.FullPath()]; ok {
+			cost = max(costFunc(c), 1)
+		}
+
+		if !allowlisted(allowlist, c.ClientIP()) {
+			subject := "ip:" + c.ClientIP()
+			limit := ratelimit.Limit{Rate: config.RequestRateLimitNum, Window: window, Burst: config.RateLimitBurst}
+			if key != nil {
+				subject = "key:" + key.ID
+				if key.RateLimit > 0 {
+					limit = keyLimit(key.RateLimit, window)
+				}
+			}
+			checks := []rateLimitCheck{{subject, limit}}
+			if rate, ok := routeLimits[c.FullPath()]; ok {
+				checks = append(checks, rateLimitCheck{"route:" + c.FullPath() + ":" + subject, ratelimit.Limit{Rate: rate, Window: window}})
+			}
+			if !allow(c, checks, cost) {
+				return
+			}
+		}
+
+		if key == nil {
+			c.Next()
+			return
+		}
+		store, _ := apikey.Default()
+		if err := store.Consume(key, cost); err != nil {
+			metrics.RateLimitRejections.WithLabelValues(routeLabel(c), "quota_exceeded").Inc()
+			common.AbortWithError(c, common.ErrQuotaExceeded.WithDetail("%d units per day, %d used", key.DailyQuota, store.DailyUsage(key.ID)))
+			return
+		}
+		c.Next()
+		if c.Writer.Status() >= http.StatusBadRequest {
+			store.Refund(key, cost)
+			return
+		}
+		ledger.Record(key.ID, c.FullPath(), cost)
+	}
+}
+
+// validateLimits 启动时检查限流配置, 窗口为 0 时每次请求的间隔为 0, 判定会除以零; 速率为 0 的限制不生效, 无需检查
+func validateLimits(window time.Duration, routeLimits map[string]int) error {
+	if window <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW: must be positive, got %d", config.RateLimitWindow)
+	}
+	if config.RateLimitBurst < 0 {
+		return fmt.Errorf("RATE_LIMIT_BURST: must not be negative, got %d", config.RateLimitBurst)
+	}
+	if config.RequestRateLimitNum > 0 {
+		if err := (ratelimit.Limit{Rate: config.RequestRateLimitNum, Window: window, Burst: config.RateLimitBurst}).Validate(); err != nil {
+			return fmt.Errorf("REQUEST_RATE_LIMIT: %w", err)
+		}
+	}
+	for path, rate := range routeLimits {
+		if rate <= 0 {
+			continue
+		}
+		if err := (ratelimit.Limit{Rate: rate, Window: window}).Validate(); err != nil {
+			return fmt.Errorf("ROUTE_RATE_LIMITS: %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// keyLimit 密钥 (含 JWT 档位) 自身的速率按 RATE_LIMIT_BURST 与 REQUEST_RATE_LIMIT 的比例设置突发上限, 未配置 RATE_LIMIT_BURST 时等于速率
+func keyLimit(rate int, window time.Duration) ratelimit.Limit {
+	limit := ratelimit.Limit{Rate: rate, Window: window}
+	if config.RateLimitBurst > 0 && config.RequestRateLimitNum > 0 {
+		limit.Burst = max(rate*config.RateLimitBurst/config.RequestRateLimitNum, 1)
+	}
+	return limit
+}
+
+// newLimiter 配置了 RATE_LIMIT_REDIS_URL 时使用 Redis, 本地限流器作为其不可用时的后备
+func newLimiter() ratelimit.Limiter {
+	memory := ratelimit.NewMemory(config.RateLimitKeyExpirationDuration)
+	if config.RateLimitRedisURL == "" {
+		return memory
+	}
+	redis, err := ratelimit.NewRedis(config.RateLimitRedisURL, "go-geoip:ratelimit:", memory)
+	if err != nil {
+		logger.FatalLog(fmt.Sprintf("RATE_LIMIT_REDIS_URL: %v", err))
 	}
+	return redis
 }
 
-func RequestRateLimit() func(c *gin.Context) {
-	return rateLimitFactory(config.RequestRateLimitNum, config.RequestRateLimitDuration, "REQUEST_RATE_LIMIT")
+// allow 依次检查各项限制, 任一项被拒绝即返回 429
+func allow(c *gin.Context, checks []rateLimitCheck, cost int) bool {
+	var strictest *ratelimit.Result
+	for _, check := range checks {
+		if check.limit.Rate <= 0 {
+			continue
+		}
+		result, err := limiter.Allow(c.Request.Context(), check.key, check.limit, cost)
+		if err != nil {
+			// 限流器不可用时放行, 避免因限流故障拒绝全部请求
+			logger.SysError(fmt.Sprintf("rate limiter: %v", err))
+			continue
+		}
+		if strictest == nil || !result.Allowed || result.Remaining < strictest.Remaining {
+			strictest = &result
+		}
+		if !result.Allowed {
+			break
+		}
+	}
+	if strictest == nil {
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(strictest.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(strictest.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(strictest.ResetAfter)))
+	if !strictest.Allowed {
+		metrics.RateLimitRejections.WithLabelValues(routeLabel(c), "rate_limited").Inc()
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(strictest.RetryAfter)))
+		common.AbortWithError(c, common.ErrRateLimited)
+		return false
+	}
+	return true
+}
+
+func routeLabel(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// parseAllowlist 单个 IP 视为 /32 或 /128
+func parseAllowlist(items []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range items {
+		prefix, err := netip.ParsePrefix(item)
+		if addr, addrErr := netip.ParseAddr(item); addrErr == nil {
+			prefix, err = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+		}
+		if err != nil {
+			logger.FatalLog(fmt.Sprintf("RATE_LIMIT_ALLOWLIST: %v", err))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func allowlisted(prefixes []netip.Prefix, ip string) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRouteLimits 解析 path=rate 列表, path 为注册时的路由 (如 /ip/:ip)
+func parseRouteLimits(items []string) map[string]int {
+	limits := map[string]int{}
+	for _, item := range items {
+		path, rate, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(rate)
+		if !ok || err != nil {
+			logger.FatalLog(fmt.Sprintf("ROUTE_RATE_LIMITS: invalid item %q", item))
+		}
+		limits[path] = n
+	}
+	return limits
 }
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"go-geoip/common/config"
+)
+
+func TestKeyLimit(t *testing.T) {
+	defer func(rate, burst int) {
+		config.RequestRateLimitNum, config.RateLimitBurst = rate, burst
+	}(config.RequestRateLimitNum, config.RateLimitBurst)
+
+	tests := []struct {
+		defaultRate, defaultBurst int
+		keyRate, wantBurst        int
+	}{
+		{120, 0, 600, 0},      // 未配置突发上限时等于速率
+		{120, 240, 600, 1200}, // 与默认限制相同的 2 倍
+		{120, 60, 600, 300},
+		{120, 60, 1, 1}, // 至少为 1
+	}
+	for _, tt := range tests {
+		config.RequestRateLimitNum, config.RateLimitBurst = tt.defaultRate, tt.defaultBurst
+		limit := keyLimit(tt.keyRate, time.Minute)
+		if limit.Rate != tt.keyRate || limit.Burst != tt.wantBurst {
+			t.Errorf("keyLimit(%d) with REQUEST_RATE_LIMIT=%d RATE_LIMIT_BURST=%d = %+v, want burst %d",
+				tt.keyRate, tt.defaultRate, tt.defaultBurst, limit, tt.wantBurst)
+		}
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	defer func(rate, window, burst int) {
+		config.RequestRateLimitNum, config.RateLimitWindow, config.RateLimitBurst = rate, window, burst
+	}(config.RequestRateLimitNum, config.RateLimitWindow, config.RateLimitBurst)
+
+	tests := []struct {
+		name                string
+		rate, window, burst int
+		routes              map[string]int
+		wantErr             bool
+	}{
+		{"defaults", 120, 60, 0, nil, false},
+		{"limiting disabled", 0, 60, 0, nil, false},
+		{"zero window", 120, 0, 0, nil, true},
+		{"negative window", 120, -1, 0, nil, true},
+		{"zero window with limiting disabled", 0, 0, 0, nil, true},
+		{"negative burst", 120, 60, -1, nil, true},
+		{"rate above one per nanosecond", 2_000_000_000, 1, 0, nil, true},
+		{"route rate above one per nanosecond", 120, 1, 0, map[string]int{"/ip/batch": 2_000_000_000}, true},
+		{"disabled route", 120, 60, 0, map[string]int{"/ip/batch": 0}, false},
+	}
+	for _, tt := range tests {
+		config.RequestRateLimitNum, config.RateLimitWindow, config.RateLimitBurst = tt.rate, tt.window, tt.burst
+		err := validateLimits(time.Duration(tt.window)*time.Second, tt.routes)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	tracing.SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	engine := gin.New()
+	engine.Use(RequestId(), Tracing())
+	engine.GET("/ip/:ip", func(c *gin.Context) {
+		// 处理函数从请求的 ctx 取得当前 span
+		if !trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid() {
+			t.Error("no span in the request context")
+		}
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/ip/8.8.8.8", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/missing", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "GET /ip/:ip" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span %q kind %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != traceID || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span did not continue the upstream trace: %v, parent %v", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.route"].AsString() != "/ip/:ip" || attrs["http.response.status_code"].AsInt64() != 503 || attrs["request_id"].AsString() == "" {
+		t.Errorf("attributes = %v", span.Attributes())
+	}
+	if span.Status().Code.String() != "Error" {
+		t.Errorf("status = %v, want Error for 5xx", span.Status())
+	}
+
+	// 未匹配的路由只以方法命名, 避免路径作为 span 名称
+	if spans[1].Name() != "POST" || spans[1].Parent().IsValid() {
+		t.Errorf("unmatched route span %q, parent %v", spans[1].Name(), spans[1].Parent())
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // ASN represents the ASN database structure.
 type ASN struct {
 	Number       uint   `maxminddb:"autonomous_system_number"`
@@ -7,9 +9,10 @@ type ASN struct {
 }
 
 // Location represents the geographical location with latitude and longitude.
+// Fields are pointers so that a record without location decodes to nil rather than 0,0.
 type Location struct {
-	Latitude  float64 `maxminddb:"latitude"`
-	Longitude float64 `maxminddb:"longitude"`
+	Latitude  *float64 `maxminddb:"latitude"`
+	Longitude *float64 `maxminddb:"longitude"`
 }
 
 // City represents the City database structure.
@@ -46,16 +49,256 @@ type GeoCN struct {
 	Net       string `maxminddb:"net"`
 }
 
+// AnonymousIP represents the GeoIP2 Anonymous IP database structure.
+type AnonymousIP struct {
+	IsAnonymous        bool `maxminddb:"is_anonymous"`
+	IsAnonymousVPN     bool `maxminddb:"is_anonymous_vpn"`
+	IsHostingProvider  bool `maxminddb:"is_hosting_provider"`
+	IsPublicProxy      bool `maxminddb:"is_public_proxy"`
+	IsResidentialProxy bool `maxminddb:"is_residential_proxy"`
+	IsTorExitNode      bool `maxminddb:"is_tor_exit_node"`
+}
+
+// Security is the security block of a lookup, merged from the Anonymous IP database and public IP lists.
+type Security struct {
+	IsAnonymous        bool     `json:"is_anonymous"`
+	IsVPN              bool     `json:"is_vpn"`
+	IsTor              bool     `json:"is_tor"`
+	IsProxy            bool     `json:"is_proxy"`
+	IsResidentialProxy bool     `json:"is_residential_proxy"`
+	IsHosting          bool     `json:"is_hosting"`
+	Sources            []string `json:"sources"`
+}
+
+// Cloud is the cloud provider attribution of a lookup, e.g. ID "aws/ec2/us-east-1".
+type Cloud struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+	Region   string `json:"region"`
+	Prefix   string `json:"prefix"`
+}
+
+// Found records which databases returned a record for the queried IP.
+type Found struct {
+	City bool `json:"city"`
+	ASN  bool `json:"asn"`
+	CN   bool `json:"cn"`
+}
+
+// Any reports whether at least one database matched.
+func (f Found) Any() bool {
+	return f.City || f.ASN || f.CN
+}
+
+// PTRRecord is a reverse DNS hostname and whether it resolves back to the queried IP.
+type PTRRecord struct {
+	Hostname         string `json:"hostname"`
+	ForwardConfirmed bool   `json:"forward_confirmed"`
+}
+
+// ReverseDNS is the optional rdns block of a lookup.
+type ReverseDNS struct {
+	Hostnames []PTRRecord `json:"hostnames"`
+	Error     string      `json:"error,omitempty"`
+}
+
 type IPInfoResponse struct {
-	Addr              string   `json:"addr" swaggertype:"string" description:"地址"`
-	AS                string   `json:"as" swaggertype:"string" description:"as"`
-	Country           string   `json:"country" swaggertype:"string" description:"国家"`
-	IP                string   `json:"ip" swaggertype:"string" description:"ip"`
-	Latitude          float64  `json:"latitude" swaggertype:"string" description:"纬度"`
-	Longitude         float64  `json:"longitude" swaggertype:"string" description:"经度"`
-	Subdivisions      []string `json:"subdivisions" swaggertype:"array,string" description:"分区"`
-	Province          string   `json:"province" swaggertype:"string" description:"省"`
-	City              string   `json:"city" swaggertype:"string" description:"市"`
-	District          string   `json:"district" swaggertype:"string" description:"区"`
-	RegisteredCountry string   `json:"registered_country" swaggertype:"string" description:"注册国家"`
+	Addr              string      `json:"addr" swaggertype:"string" description:"地址"`
+	AS                string      `json:"as" swaggertype:"string" description:"as"`
+	ASN               uint        `json:"asn" swaggertype:"integer" description:"AS 号"`
+	Country           string      `json:"country" swaggertype:"string" description:"国家"`
+	CountryCode       string      `json:"country_code" swaggertype:"string" description:"国家代码"`
+	IP                string      `json:"ip" swaggertype:"string" description:"规范化后的 ip, IPv4 映射地址返回 IPv4 形式"`
+	Version           int         `json:"version" swaggertype:"integer" description:"IP 版本, 4 或 6"`
+	Latitude          *float64    `json:"latitude" swaggertype:"number" description:"纬度, 无数据时为 null"`
+	Longitude         *float64    `json:"longitude" swaggertype:"number" description:"经度, 无数据时为 null"`
+	Subdivisions      []string    `json:"subdivisions" swaggertype:"array,string" description:"分区"`
+	Province          string      `json:"province" swaggertype:"string" description:"省"`
+	City              string      `json:"city" swaggertype:"string" description:"市"`
+	District          string      `json:"district" swaggertype:"string" description:"区"`
+	RegisteredCountry string      `json:"registered_country" swaggertype:"string" description:"注册国家"`
+	Found             Found       `json:"found" description:"各数据库是否命中"`
+	RDNS              *ReverseDNS `json:"rdns,omitempty" description:"反向解析结果, 仅 rdns=1 时返回"`
+	Security          *Security   `json:"security,omitempty" description:"匿名/VPN/Tor/代理/托管标记, 仅配置了安全数据源时返回"`
+	Cloud             *Cloud      `json:"cloud,omitempty" description:"云厂商/服务/区域, 仅命中云厂商 IP 段时返回"`
+}
+
+// CityRecord is the JSON form of a City database record.
+type CityRecord struct {
+	Country           string   `json:"country"`
+	CountryCode       string   `json:"country_code"`
+	RegisteredCountry string   `json:"registered_country"`
+	Subdivisions      []string `json:"subdivisions"`
+	City              string   `json:"city"`
+	Latitude          *float64 `json:"latitude" swaggertype:"number"`
+	Longitude         *float64 `json:"longitude" swaggertype:"number"`
+}
+
+// ASNRecord is the JSON form of an ASN database record.
+type ASNRecord struct {
+	Number       uint   `json:"asn"`
+	Organization string `json:"organization"`
+}
+
+// CNRecord is the JSON form of a GeoCN database record.
+type CNRecord struct {
+	Province string `json:"province"`
+	City     string `json:"city"`
+	District string `json:"district"`
+	ISP      string `json:"isp"`
+	Net      string `json:"net"`
+}
+
+// NetworkEntry is one database network inside a queried prefix.
+type NetworkEntry struct {
+	Database string      `json:"database" swaggertype:"string" description:"city/asn/cn"`
+	Network  string      `json:"network"`
+	Record   interface{} `json:"record" description:"CityRecord/ASNRecord/CNRecord"`
+}
+
+type NetworkListResponse struct {
+	Network    string         `json:"network" description:"查询的网段"`
+	Databases  []string       `json:"databases"`
+	Offset     int            `json:"offset"`
+	Limit      int            `json:"limit"`
+	NextOffset *int           `json:"next_offset" description:"下一页 offset, 没有更多数据时为 null"`
+	Networks   []NetworkEntry `json:"networks"`
+}
+
+// ASNInfo is an autonomous system with every prefix the ASN database assigns to it.
+type ASNInfo struct {
+	Number        uint     `json:"asn"`
+	Organization  string   `json:"organization"`
+	IPv4Prefixes  []string `json:"ipv4_prefixes"`
+	IPv6Prefixes  []string `json:"ipv6_prefixes"`
+	IPv4Addresses uint64   `json:"ipv4_addresses" description:"IPv4 地址数"`
+	IPv6Slash64s  uint64   `json:"ipv6_slash64s" description:"IPv6 /64 网段数"`
+}
+
+// ASNSummary is an ASNInfo without its prefix lists, used in search results.
+type ASNSummary struct {
+	Number        uint   `json:"asn"`
+	Organization  string `json:"organization"`
+	PrefixCount   int    `json:"prefix_count"`
+	IPv4Addresses uint64 `json:"ipv4_addresses"`
+	IPv6Slash64s  uint64 `json:"ipv6_slash64s"`
+}
+
+type ASNSearchResponse struct {
+	Org        string       `json:"org"`
+	Total      int          `json:"total"`
+	Offset     int          `json:"offset"`
+	Limit      int          `json:"limit"`
+	NextOffset *int         `json:"next_offset"`
+	Results    []ASNSummary `json:"results"`
+}
+
+type CountryNetworksResponse struct {
+	Country    string   `json:"country"`
+	BuildEpoch uint     `json:"build_epoch" description:"City 数据库构建时间"`
+	IPv4       []string `json:"ipv4"`
+	IPv6       []string `json:"ipv6"`
+}
+
+// ExportRecord is one row of the merged City/ASN/GeoCN export; the JSON names are also the CSV header.
+type ExportRecord struct {
+	Network           string   `json:"network"`
+	Country           string   `json:"country"`
+	CountryCode       string   `json:"country_code"`
+	RegisteredCountry string   `json:"registered_country"`
+	Subdivisions      []string `json:"subdivisions"`
+	Province          string   `json:"province"`
+	City              string   `json:"city"`
+	District          string   `json:"district"`
+	Latitude          *float64 `json:"latitude"`
+	Longitude         *float64 `json:"longitude"`
+	ASN               uint     `json:"asn"`
+	AS                string   `json:"as"`
+}
+
+// DatabaseInfo is the metadata of a loaded MMDB file.
+type DatabaseInfo struct {
+	Name         string            `json:"name"`
+	File         string            `json:"file"`
+	Loaded       bool              `json:"loaded"`
+	DatabaseType string            `json:"database_type,omitempty"`
+	BuildEpoch   uint              `json:"build_epoch,omitempty"`
+	BuildTime    string            `json:"build_time,omitempty"`
+	IPVersion    uint              `json:"ip_version,omitempty"`
+	NodeCount    uint              `json:"node_count,omitempty"`
+	RecordSize   uint              `json:"record_size,omitempty"`
+	Languages    []string          `json:"languages,omitempty"`
+	Description  map[string]string `json:"description,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+type BatchRequest struct {
+	IPs []string `json:"ips" binding:"required"`
+}
+
+// BatchResult is the lookup result of one IP in a batch; exactly one of Data and Error is set.
+type BatchResult struct {
+	IP      string          `json:"ip"`
+	Data    *IPInfoResponse `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty" description:"错误码 key, 如 invalid_ip"`
+	Code    int             `json:"code,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+type ServiceInfo struct {
+	Version   string         `json:"version"`
+	Databases []DatabaseInfo `json:"databases"`
+}
+
+// DualStackResponse tells a browser which family-specific base URLs to call /ip on.
+type DualStackResponse struct {
+	IP      string `json:"ip" description:"本次请求的客户端 IP"`
+	Version int    `json:"version" description:"本次请求使用的 IP 版本, 4 或 6; 无法解析时为 0"`
+	IPv4URL string `json:"ipv4_url" description:"仅解析到 IPv4 的基础 URL, 未配置时为空"`
+	IPv6URL string `json:"ipv6_url" description:"仅解析到 IPv6 的基础 URL, 未配置时为空"`
+}
+
+// APIKey is an API key as returned by the admin endpoints; the secret hash is never exposed.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit" description:"每个限流窗口 (RATE_LIMIT_WINDOW) 的请求数, 0 使用 REQUEST_RATE_LIMIT"`
+	DailyQuota int        `json:"daily_quota" description:"每个 UTC 日的计费单位 (批量查询按 IP 数计), 0 为不限"`
+	Active     bool       `json:"active" description:"未吊销且未过期"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCreateRequest is the body of POST /admin/keys.
+type APIKeyCreateRequest struct {
+	Label      string     `json:"label"`
+	Scopes     []string   `json:"scopes" description:"lookup/batch/network/asn/country/export/admin, * 为除 admin 外的全部权限; 默认 *"`
+	RateLimit  int        `json:"rate_limit"`
+	DailyQuota int        `json:"daily_quota"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" description:"RFC 3339 时间, 为空则不过期"`
+}
+
+// APIKeySecretResponse carries the plaintext secret, returned only on create and rotate.
+type APIKeySecretResponse struct {
+	Key    APIKey `json:"key"`
+	Secret string `json:"secret" description:"明文密钥, 仅返回一次"`
+}
+
+// UsageResponse reports the consumption of one key: the monthly ledger plus today's quota.
+type UsageResponse struct {
+	KeyID      string           `json:"key_id"`
+	Month      string           `json:"month" description:"UTC 月份, 如 2026-10"`
+	Requests   int64            `json:"requests" description:"该月的请求数"`
+	Units      int64            `json:"units" description:"该月的计费单位, 批量查询按 IP 数计"`
+	Routes     map[string]int64 `json:"routes" description:"按路由汇总的计费单位"`
+	DailyQuota int              `json:"daily_quota" description:"每日配额, 0 为不限"`
+	DailyUsed  int              `json:"daily_used" description:"今日 (UTC) 已使用的计费单位"`
 }
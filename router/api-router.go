@@ -1,29 +1,111 @@
 package router
 
 import (
+	"slices"
+
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go-geoip/common/apikey"
 	"go-geoip/common/config"
 	"go-geoip/controller"
 	_ "go-geoip/docs"
 	"go-geoip/middleware"
 )
 
+// Policy 路由的访问策略
+type Policy int
+
+const (
+	// Public 无需密钥, 携带的有效密钥仍计入该密钥的限流与配额
+	Public Policy = iota
+	// KeyRequired 创建过密钥或配置了 API_SECRET 后需要具备 Scope 的密钥
+	KeyRequired
+	// AdminOnly 始终需要具备 admin 权限的密钥
+	AdminOnly
+)
+
+// Route 一条 API 路由及其访问策略
+type Route struct {
+	Method  string
+	Path    string
+	Policy  Policy
+	Scope   string
+	Handler gin.HandlerFunc
+}
+
+// Routes 全部 API 路由; 调整访问策略时同步修改对应接口 swagger 注释中的 @Security
+var Routes = []Route{
+	{"GET", "/ip", Public, "", controller.IpNoArgs},
+	{"GET", "/dualstack", Public, "", controller.DualStack},
+	{"GET", "/dualstack.js", Public, "", controller.DualStackJS},
+
+	{"GET", "/ip/:ip", KeyRequired, apikey.ScopeLookup, controller.Ip},
+	{"POST", "/ip/batch", KeyRequired, apikey.ScopeBatch, controller.IpBatch},
+	{"GET", "/info", KeyRequired, "", controller.Info},
+	{"GET", "/network/*cidr", KeyRequired, apikey.ScopeNetwork, controller.Network},
+	{"GET", "/asn", KeyRequired, apikey.ScopeASN, controller.AsnSearch},
+	{"GET", "/asn/:number", KeyRequired, apikey.ScopeASN, controller.Asn},
+	{"GET", "/country/:iso/networks", KeyRequired, apikey.ScopeCountry, controller.CountryNetworks},
+	{"GET", "/export", KeyRequired, apikey.ScopeExport, controller.Export},
+	{"GET", "/usage", KeyRequired, "", controller.Usage},
+
+	{"GET", "/admin/keys", AdminOnly, apikey.ScopeAdmin, controller.ListKeys},
+	{"POST", "/admin/keys", AdminOnly, apikey.ScopeAdmin, controller.CreateKey},
+	{"POST", "/admin/keys/:id/rotate", AdminOnly, apikey.ScopeAdmin, controller.RotateKey},
+	{"DELETE", "/admin/keys/:id", AdminOnly, apikey.ScopeAdmin, controller.RevokeKey},
+
+	// Prometheus 抓取时以 bearer_token 携带 admin 密钥, METRICS_PUBLIC=true 时无需密钥
+	{"GET", "/metrics", AdminOnly, apikey.ScopeAdmin, gin.WrapH(promhttp.Handler())},
+}
+
+// routeCosts 按计费单位而非请求次数扣除令牌与配额的路由
+var routeCosts = map[string]middleware.CostFunc{
+	"/ip/batch": controller.BatchCost,
+}
+
 func SetApiRouter(router *gin.Engine) {
 
 	// 全局 Middlewares
 	router.Use(middleware.CORS())
-	router.Use(middleware.RequestRateLimit())
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Tracing())
 
 	if config.SwaggerEnable == "" || config.SwaggerEnable == "1" {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
-	// 启用身份验证中间件
+	// 识别密钥, 是否必须携带由各路由的 Policy 决定; 识别后按密钥或客户端 IP 限流
 	router.Use(middleware.Auth())
+	router.Use(middleware.RateLimit(routeCosts))
+
+	for _, route := range Routes {
+		if route.Path == "/metrics" && !config.MetricsEnable {
+			continue
+		}
+		router.Handle(route.Method, route.Path, handlers(route)...)
+	}
+}
 
-	// 无需身份验证的路由
-	router.GET("/ip", controller.IpNoArgs)
-	router.GET("/ip/:ip", controller.Ip)
+func handlers(route Route) []gin.HandlerFunc {
+	switch routePolicy(route) {
+	case KeyRequired:
+		return []gin.HandlerFunc{middleware.RequireScope(route.Scope), route.Handler}
+	case AdminOnly:
+		return []gin.HandlerFunc{middleware.RequireScope(apikey.ScopeAdmin), route.Handler}
+	default:
+		return []gin.HandlerFunc{route.Handler}
+	}
+}
+
+// routePolicy 按配置调整后的访问策略: PUBLIC_ROUTES 只能开放 KeyRequired 路由, 管理接口不受影响
+func routePolicy(route Route) Policy {
+	switch {
+	case route.Policy == KeyRequired && slices.Contains(config.PublicRoutes, route.Path):
+		return Public
+	case route.Path == "/metrics" && config.MetricsPublic:
+		return Public
+	}
+	return route.Policy
 }
@@ -2,8 +2,12 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"go-geoip/common"
 )
 
 func SetRouter(router *gin.Engine) {
 	SetApiRouter(router)
+	router.NoRoute(func(c *gin.Context) {
+		common.SendError(c, common.ErrNotFound.WithDetail("%s", c.Request.URL.Path))
+	})
 }
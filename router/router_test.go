@@ -0,0 +1,180 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-geoip/common/apikey"
+	"go-geoip/common/config"
+	"go-geoip/controller"
+	"go-geoip/geoip/geoiptest"
+	"go-geoip/middleware"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	config.APIKeysFile = ""
+	config.UsageFile = ""
+	os.Exit(m.Run())
+}
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	controller.SetEngine(geoiptest.NewEngine(t))
+	engine := gin.New()
+	engine.Use(middleware.RequestId())
+	SetRouter(engine)
+	return engine
+}
+
+func createKey(t *testing.T, spec apikey.Spec) (*apikey.Key, string) {
+	t.Helper()
+	store, err := apikey.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, secret, err := store.Create(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, secret
+}
+
+func serve(engine *gin.Engine, method, path, secret, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// 配额与账本只计入成功的请求: 缺少权限 (403) 与参数错误 (400) 的请求不消耗配额
+func TestBillingOnlyForSuccessfulRequests(t *testing.T) {
+	engine := newTestRouter(t)
+	ledger, _ := apikey.DefaultLedger()
+	store, _ := apikey.Default()
+	month := time.Now().UTC().Format(apikey.MonthFormat)
+	batch := `{"ips": ["8.8.8.8", "1.1.1.1", "1.0.1.1"]}`
+
+	lookupOnly, lookupSecret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeLookup}, DailyQuota: 100})
+	if w := serve(engine, "POST", "/ip/batch", lookupSecret, batch); w.Code != http.StatusForbidden {
+		t.Fatalf("batch without scope: status %d, want 403", w.Code)
+	}
+	if used := store.DailyUsage(lookupOnly.ID); used != 0 {
+		t.Errorf("forbidden batch consumed %d units", used)
+	}
+
+	batchKey, batchSecret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeBatch}, DailyQuota: 100})
+	if w := serve(engine, "POST", "/ip/batch", batchSecret, `{"ips": 1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid batch: status %d, want 400", w.Code)
+	}
+	if used := store.DailyUsage(batchKey.ID); used != 0 {
+		t.Errorf("invalid batch consumed %d units", used)
+	}
+
+	if w := serve(engine, "POST", "/ip/batch", batchSecret, batch); w.Code != http.StatusOK {
+		t.Fatalf("batch: status %d, want 200: %s", w.Code, w.Body)
+	}
+	if used := store.DailyUsage(batchKey.ID); used != 3 {
+		t.Errorf("batch of 3 consumed %d units, want 3", used)
+	}
+	if usage := ledger.Get(batchKey.ID, month); usage.Requests != 1 || usage.Units != 3 {
+		t.Errorf("ledger = %+v, want 1 request and 3 units", usage)
+	}
+	if usage := ledger.Get(lookupOnly.ID, month); usage.Requests != 0 {
+		t.Errorf("ledger recorded the forbidden request: %+v", usage)
+	}
+}
+
+func TestBatchBodyLimit(t *testing.T) {
+	engine := newTestRouter(t)
+	_, secret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeBatch}})
+
+	body := `{"ips": ["` + strings.Repeat("1", 1<<20) + `"]}`
+	if w := serve(engine, "POST", "/ip/batch", secret, body); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized body: status %d, want 400", w.Code)
+	}
+}
+
+// 集合名原样写入防火墙与 nginx 配置, 含换行或分号的名称会被拒绝
+func TestCountryNetworksName(t *testing.T) {
+	engine := newTestRouter(t)
+	_, secret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeCountry}})
+
+	// 加上 _v4/_v6 后缀超过 ipset 的 31 个字符, 或以数字开头 (nginx 变量名无效)
+	for _, name := range []string{"geoip_jp;flush ruleset", "a\nflush ruleset", "", strings.Repeat("a", 29), "1abc"} {
+		path := "/country/JP/networks?format=nftables&name=" + url.QueryEscape(name)
+		if w := serve(engine, "GET", path, secret, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("name %q: status %d, want 400", name, w.Code)
+		}
+	}
+
+	for _, name := range []string{strings.Repeat("a", 28), "_jp", "JP_2"} {
+		if w := serve(engine, "GET", "/country/JP/networks?format=ipset&name="+name, secret, ""); w.Code != http.StatusOK {
+			t.Errorf("name %q: status %d, want 200", name, w.Code)
+		}
+	}
+
+	w := serve(engine, "GET", "/country/JP/networks?format=nftables&name=jp", secret, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "set jp_v4") || !strings.Contains(w.Body.String(), "203.0.113.0/24") {
+		t.Errorf("status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestMetricsPolicy(t *testing.T) {
+	defer func(enable, public bool) {
+		config.MetricsEnable, config.MetricsPublic = enable, public
+	}(config.MetricsEnable, config.MetricsPublic)
+	_, lookupSecret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeLookup}})
+	_, adminSecret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeAdmin}})
+
+	engine := newTestRouter(t)
+	for secret, want := range map[string]int{"": http.StatusUnauthorized, lookupSecret: http.StatusForbidden, adminSecret: http.StatusOK} {
+		if w := serve(engine, "GET", "/metrics", secret, ""); w.Code != want {
+			t.Errorf("secret %q: status %d, want %d", secret, w.Code, want)
+		}
+	}
+
+	config.MetricsPublic = true
+	engine = newTestRouter(t)
+	if w := serve(engine, "GET", "/metrics", "", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "geoip_http_requests_total") {
+		t.Errorf("public metrics: status %d", w.Code)
+	}
+
+	config.MetricsEnable = false
+	engine = newTestRouter(t)
+	if w := serve(engine, "GET", "/metrics", adminSecret, ""); w.Code != http.StatusNotFound {
+		t.Errorf("disabled metrics: status %d, want 404", w.Code)
+	}
+}
+
+func TestNetworkPagination(t *testing.T) {
+	defer func(maxOffset int) { config.NetworkMaxOffset = maxOffset }(config.NetworkMaxOffset)
+	config.NetworkMaxOffset = 10
+	engine := newTestRouter(t)
+	_, secret := createKey(t, apikey.Spec{Scopes: []string{apikey.ScopeNetwork}})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/network/203.0.113.0/24?offset=10", http.StatusOK},
+		{"/network/203.0.113.0/24?offset=11", http.StatusBadRequest},
+		{"/network/203.0.113.0/24?offset=-1", http.StatusBadRequest},
+		// 包含只有 IPv4 的 GeoCN 数据库
+		{"/network/2001:4860::/32", http.StatusOK},
+		{"/network/2001:4860::/32?db=cn", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := serve(engine, "GET", tt.path, secret, ""); w.Code != tt.code {
+			t.Errorf("%s: status %d, want %d: %s", tt.path, w.Code, tt.code, w.Body)
+		}
+	}
+}